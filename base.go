@@ -24,6 +24,7 @@ type Base struct {
 	Static
 }
 
+// NewBase return an empty set hold items in [min,max].
 func NewBase(max, min int) *Base {
 	var b Base
 	b.onciInit(max, min)
@@ -41,6 +42,13 @@ func (s *Base) onciInit(max, min int) {
 	})
 }
 
+// FullBase return a set hold all items in [min,max].
+func FullBase(max, min int) *Base {
+	b := NewBase(max, min)
+	b.AddRange(atomic.LoadInt32(&b.min), atomic.LoadInt32(&b.max))
+	return b
+}
+
 // Init once time with max item.
 func (s *Base) Init(max, min int) {
 	s.onciInit(max, min)
@@ -71,6 +79,40 @@ func (s *Base) Remove(x int32) (loaded bool) {
 	return
 }
 
+// AddRange adds all items in [lo,hi] to the set.
+// return false if lo or hi overflow with min and max.
+func (s *Base) AddRange(lo, hi int32) bool {
+	if lo < atomic.LoadInt32(&s.min) {
+		return false
+	}
+	return s.Static.AddRange(s.move(lo), s.move(hi))
+}
+
+// RemoveRange remove all items in [lo,hi] from the set.
+// return false if lo or hi overflow with min and max.
+func (s *Base) RemoveRange(lo, hi int32) bool {
+	if lo < atomic.LoadInt32(&s.min) {
+		return false
+	}
+	return s.Static.RemoveRange(s.move(lo), s.move(hi))
+}
+
+// FlipRange toggle all items in [lo,hi].
+// return false if lo or hi overflow with min and max.
+func (s *Base) FlipRange(lo, hi int32) bool {
+	if lo < atomic.LoadInt32(&s.min) {
+		return false
+	}
+	return s.Static.FlipRange(s.move(lo), s.move(hi))
+}
+
+// Not return a new set of the items in [min,max] but not in s.
+func (s *Base) Not() *Base {
+	p := NewBase(int(atomic.LoadInt32(&s.max)), int(atomic.LoadInt32(&s.min)))
+	notInto(&p.Static, &s.Static, uint32(atomic.LoadInt32(&p.cap)-1))
+	return p
+}
+
 // Range calls f sequentially for each item present in the set.
 // If f returns false, range stops the iteration.
 func (s *Base) Range(f func(x int32) bool) {
@@ -80,6 +122,105 @@ func (s *Base) Range(f func(x int32) bool) {
 	})
 }
 
+// Min return the smallest item in the set.
+// ok is false if the set is empty.
+func (s *Base) Min() (x int32, ok bool) {
+	n, ok := s.Static.Min()
+	if !ok {
+		return 0, false
+	}
+	return atomic.LoadInt32(&s.min) + int32(n), true
+}
+
+// Max return the biggest item in the set.
+// ok is false if the set is empty.
+func (s *Base) Max() (x int32, ok bool) {
+	n, ok := s.Static.Max()
+	if !ok {
+		return 0, false
+	}
+	return atomic.LoadInt32(&s.min) + int32(n), true
+}
+
+// Next return the smallest item in the set at or after x.
+// ok is false if no such item.
+func (s *Base) Next(x int32) (next int32, ok bool) {
+	min := atomic.LoadInt32(&s.min)
+	if x < min {
+		x = min
+	}
+	n, ok := s.Static.Next(s.move(x))
+	if !ok {
+		return 0, false
+	}
+	return min + int32(n), true
+}
+
+// Prev return the biggest item in the set at or before x.
+// ok is false if no such item.
+func (s *Base) Prev(x int32) (prev int32, ok bool) {
+	min := atomic.LoadInt32(&s.min)
+	if x < min {
+		return 0, false
+	}
+	n, ok := s.Static.Prev(s.move(x))
+	if !ok {
+		return 0, false
+	}
+	return min + int32(n), true
+}
+
+// RangeFrom calls f sequentially for each item >= lo in the set.
+// If f returns false, range stops the iteration.
+func (s *Base) RangeFrom(lo int32, f func(x int32) bool) {
+	s.RangeBetween(lo, atomic.LoadInt32(&s.max), f)
+}
+
+// RangeBetween calls f sequentially for each item in [lo,hi] of the set.
+// If f returns false, range stops the iteration.
+func (s *Base) RangeBetween(lo, hi int32, f func(x int32) bool) {
+	lo, hi, ok := s.clamp(lo, hi)
+	if !ok {
+		return
+	}
+	min := atomic.LoadInt32(&s.min)
+	s.Static.RangeBetween(s.move(lo), s.move(hi), func(x uint32) bool {
+		return f(min + int32(x))
+	})
+}
+
+// RangeReverse calls f sequentially for each item in the set,
+// from the biggest to the smallest.
+// If f returns false, range stops the iteration.
+func (s *Base) RangeReverse(f func(x int32) bool) {
+	s.RangeReverseBetween(atomic.LoadInt32(&s.min), atomic.LoadInt32(&s.max), f)
+}
+
+// RangeReverseBetween calls f sequentially for each item in [lo,hi] of the set,
+// from hi to lo.
+// If f returns false, range stops the iteration.
+func (s *Base) RangeReverseBetween(lo, hi int32, f func(x int32) bool) {
+	lo, hi, ok := s.clamp(lo, hi)
+	if !ok {
+		return
+	}
+	min := atomic.LoadInt32(&s.min)
+	s.Static.RangeReverseBetween(s.move(lo), s.move(hi), func(x uint32) bool {
+		return f(min + int32(x))
+	})
+}
+
+// clamp limit [lo,hi] to [min,max], ok is false if empty.
+func (s *Base) clamp(lo, hi int32) (int32, int32, bool) {
+	if min := atomic.LoadInt32(&s.min); lo < min {
+		lo = min
+	}
+	if max := atomic.LoadInt32(&s.max); hi > max {
+		hi = max
+	}
+	return lo, hi, lo <= hi
+}
+
 // String returns the set as a string of the form "{1 2 3}".
 func (s *Base) String() string {
 	var buf bytes.Buffer
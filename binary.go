@@ -0,0 +1,293 @@
+package set
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math/bits"
+	"sync/atomic"
+)
+
+// binary format of a set:
+//
+//	magic   [3]byte "set"
+//	version byte
+//	kind    byte  kindStatic,kindDynamic or kindBase
+//	enc     byte  encDense or encSparse
+//	max     uvarint
+//	min     varint, only Base not 0
+//	count   uvarint number of items
+//	payload
+//	crc     [4]byte crc32 IEEE of all bytes before, little endian
+//
+// encDense payload: uvarint n, n little endian uint64 words,
+// bit j of word i is item 64*i+j.
+// encSparse payload: count uvarints, the first item then the delta to prev item.
+const (
+	binaryMagic   = "set"
+	binaryVersion = 1
+)
+
+const (
+	kindStatic byte = iota + 1
+	kindDynamic
+	kindBase
+)
+
+const (
+	encDense byte = iota
+	encSparse
+)
+
+var (
+	// ErrFormat is returned when decode data not a valid set.
+	ErrFormat = errors.New("set: invalid binary format")
+
+	// ErrVersion is returned when decode data of unknown version.
+	ErrVersion = errors.New("set: unknown binary version")
+
+	// ErrChecksum is returned when decode data with wrong checksum.
+	ErrChecksum = errors.New("set: checksum mismatch")
+
+	// ErrKind is returned when decode data of a set can't convert to.
+	ErrKind = errors.New("set: incompatible set kind")
+
+	// ErrOverflow is returned when decode items bigger than max of the set.
+	ErrOverflow = errors.New("set: item overflow")
+)
+
+// binaryHeader is the header of binary format.
+type binaryHeader struct {
+	kind  byte
+	enc   byte
+	max   uint32
+	min   int32
+	count int
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *Static) MarshalBinary() ([]byte, error) {
+	s.onceInit(initSize)
+	return marshalBinary(kindStatic, s, s.getMax(), 0), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// data can be encode by Static or Dynamic,
+// the set is init with max of data if not init yet, and cleared before load.
+func (s *Static) UnmarshalBinary(data []byte) error {
+	h, payload, err := decodeHeader(data)
+	if err != nil {
+		return err
+	}
+	if h.kind == kindBase {
+		return ErrKind
+	}
+	s.OnceInit(int(h.max))
+	Clear(s)
+	return decodePayload(h, payload, s.StoreMany)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *Dynamic) MarshalBinary() ([]byte, error) {
+	return marshalBinary(kindDynamic, s.getEntry(), s.getMax(), 0), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// data can be encode by Static or Dynamic,
+// the set is init with max of data if not init yet, and cleared before load.
+func (s *Dynamic) UnmarshalBinary(data []byte) error {
+	h, payload, err := decodeHeader(data)
+	if err != nil {
+		return err
+	}
+	if h.kind == kindBase {
+		return ErrKind
+	}
+	if h.max >= maximum {
+		// grow as need, not preallocate the whole range.
+		s.OnceInit(0)
+	} else {
+		s.OnceInit(int(h.max))
+	}
+	Clear(s)
+	return decodePayload(h, payload, s.StoreMany)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *Base) MarshalBinary() ([]byte, error) {
+	return marshalBinary(kindBase, &s.Static, uint32(atomic.LoadInt32(&s.max)), atomic.LoadInt32(&s.min)), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// data must be encode by Base,
+// the set is init with max and min of data if not init yet, and cleared before load.
+func (s *Base) UnmarshalBinary(data []byte) error {
+	h, payload, err := decodeHeader(data)
+	if err != nil {
+		return err
+	}
+	if h.kind != kindBase {
+		return ErrKind
+	}
+	s.Init(int(int32(h.max)), int(h.min))
+	Clear(&s.Static)
+	min := atomic.LoadInt32(&s.min)
+	return decodePayload(h, payload, func(xs []uint32) bool {
+		ok := true
+		for _, x := range xs {
+			// move from min of data to min of s.
+			v := h.min + int32(x)
+			if v < min || v > atomic.LoadInt32(&s.max) {
+				ok = false
+				continue
+			}
+			s.Static.Store(uint32(v - min))
+		}
+		return ok
+	})
+}
+
+// marshalBinary encode the words of s with the smaller of dense and sparse payload.
+func marshalBinary(kind byte, s bitSet, max uint32, base int32) []byte {
+	// dense words, trim the empty tail.
+	n := wordCount(s, 64)
+	words := make([]uint64, n)
+	count := 0
+	for i := range words {
+		words[i] = uint64(loadWord(s, 2*i, 32)) | uint64(loadWord(s, 2*i+1, 32))<<32
+		count += bits.OnesCount64(words[i])
+	}
+	for n > 0 && words[n-1] == 0 {
+		n -= 1
+	}
+	words = words[:n]
+
+	// sparse delta list, give up if bigger than dense.
+	denseSize := binary.MaxVarintLen64 + 8*n
+	sparse := make([]byte, 0, min(denseSize, 5*count))
+	enc := encSparse
+	prev := uint64(0)
+	for i, w := range words {
+		for ; w != 0; w &= w - 1 {
+			x := uint64(i)*64 + uint64(bits.TrailingZeros64(w))
+			sparse = binary.AppendUvarint(sparse, x-prev)
+			prev = x
+		}
+		if len(sparse) >= denseSize {
+			enc = encDense
+			break
+		}
+	}
+
+	buf := make([]byte, 0, 32+min(len(sparse), denseSize))
+	buf = append(buf, binaryMagic...)
+	buf = append(buf, binaryVersion, kind, enc)
+	buf = binary.AppendUvarint(buf, uint64(max))
+	buf = binary.AppendVarint(buf, int64(base))
+	buf = binary.AppendUvarint(buf, uint64(count))
+	if enc == encSparse {
+		buf = append(buf, sparse...)
+	} else {
+		buf = binary.AppendUvarint(buf, uint64(n))
+		for _, w := range words {
+			buf = binary.LittleEndian.AppendUint64(buf, w)
+		}
+	}
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+}
+
+// decodeHeader verify the checksum of data,
+// and return the header and payload.
+func decodeHeader(data []byte) (h binaryHeader, payload []byte, err error) {
+	if len(data) < len(binaryMagic)+3+4 || string(data[:len(binaryMagic)]) != binaryMagic {
+		return h, nil, ErrFormat
+	}
+	body, sum := data[:len(data)-4], data[len(data)-4:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(sum) {
+		return h, nil, ErrChecksum
+	}
+	p := body[len(binaryMagic):]
+	if p[0] != binaryVersion {
+		return h, nil, ErrVersion
+	}
+	h.kind, h.enc = p[1], p[2]
+	if h.kind < kindStatic || h.kind > kindBase || h.enc > encSparse {
+		return h, nil, ErrFormat
+	}
+	p = p[3:]
+	max, n := binary.Uvarint(p)
+	if n <= 0 || max > 1<<32-1 {
+		return h, nil, ErrFormat
+	}
+	p = p[n:]
+	min, n := binary.Varint(p)
+	if n <= 0 || min != int64(int32(min)) {
+		return h, nil, ErrFormat
+	}
+	p = p[n:]
+	count, n := binary.Uvarint(p)
+	if n <= 0 || count > 1<<32 {
+		return h, nil, ErrFormat
+	}
+	h.max, h.min, h.count = uint32(max), int32(min), int(count)
+	return h, p[n:], nil
+}
+
+// decodePayload call f with the items of payload in batch.
+// f return false if some items overflow.
+func decodePayload(h binaryHeader, payload []byte, f func(xs []uint32) bool) error {
+	var buf [256]uint32
+	xs := buf[:0]
+	ok := true
+	flush := func() {
+		if len(xs) > 0 && !f(xs) {
+			ok = false
+		}
+		xs = xs[:0]
+	}
+	if h.enc == encSparse {
+		x := uint64(0)
+		for i := 0; i < h.count; i++ {
+			d, n := binary.Uvarint(payload)
+			if n <= 0 || (i > 0 && d == 0) {
+				return ErrFormat
+			}
+			payload = payload[n:]
+			x += d
+			if x > 1<<32-1 {
+				return ErrFormat
+			}
+			if xs = append(xs, uint32(x)); len(xs) == len(buf) {
+				flush()
+			}
+		}
+	} else {
+		n, m := binary.Uvarint(payload)
+		if m <= 0 || n > uint64(len(payload)-m)/8 || n > 1<<26 {
+			return ErrFormat
+		}
+		payload = payload[m:]
+		count := 0
+		for i := 0; i < int(n); i++ {
+			w := binary.LittleEndian.Uint64(payload)
+			payload = payload[8:]
+			count += bits.OnesCount64(w)
+			for ; w != 0; w &= w - 1 {
+				if xs = append(xs, uint32(i*64+bits.TrailingZeros64(w))); len(xs) == len(buf) {
+					flush()
+				}
+			}
+		}
+		if count != h.count {
+			return ErrFormat
+		}
+	}
+	if len(payload) != 0 {
+		return ErrFormat
+	}
+	flush()
+	if !ok {
+		return ErrOverflow
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package set_test
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+type binarySet interface {
+	Interface
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+func TestMarshalBinary(t *testing.T) {
+	sparse := []uint32{0, 7, 300, 9999}
+	var dense []uint32
+	for i := uint32(100); i < 5000; i++ {
+		if i%3 != 0 {
+			dense = append(dense, i)
+		}
+	}
+	for _, items := range [][]uint32{nil, sparse, dense} {
+		for _, src := range []func() binarySet{
+			func() binarySet { return &set.Static{} },
+			func() binarySet { return &set.Dynamic{} },
+		} {
+			for _, dst := range []func() binarySet{
+				func() binarySet { return &set.Static{} },
+				func() binarySet { return &set.Dynamic{} },
+			} {
+				s, d := src(), dst()
+				t.Run(fmt.Sprintf("%T-%T-%d", s, d, len(items)), func(t *testing.T) {
+					s.OnceInit(10000)
+					set.Adds(s, items...)
+					data, err := s.MarshalBinary()
+					if err != nil {
+						t.Fatal(err)
+					}
+					// not init yet, use max of data.
+					if err := d.UnmarshalBinary(data); err != nil {
+						t.Fatal(err)
+					}
+					if !set.Equal(s, d) || set.Size(d) != len(items) {
+						t.Fatalf("UnmarshalBinary() = %v want %v", d, s)
+					}
+					if !d.Store(10000) || d.Store(10001) {
+						t.Fatalf("UnmarshalBinary() max err")
+					}
+					// load clear the items before.
+					if err := d.UnmarshalBinary(data); err != nil || set.Size(d) != len(items) {
+						t.Fatalf("UnmarshalBinary() again = %v %v", d, err)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestMarshalBinarySize(t *testing.T) {
+	var s set.Static
+	s.OnceInit(1 << 20)
+	set.Adds(&s, 1, 1<<19, 1<<20)
+	data, _ := s.MarshalBinary()
+	if len(data) > 32 {
+		t.Fatalf("sparse size = %d", len(data))
+	}
+	s.AddRange(0, 1<<20)
+	data, _ = s.MarshalBinary()
+	if want := (1<<20)/8 + 32; len(data) > want {
+		t.Fatalf("dense size = %d want <= %d", len(data), want)
+	}
+}
+
+func TestMarshalBinaryBase(t *testing.T) {
+	b := set.NewBase(100, -100)
+	for _, x := range []int32{-100, -3, 40, 100} {
+		b.Add(x)
+	}
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c set.Base
+	if err := c.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if c.String() != b.String() || !c.Has(-100) || c.Add(101) {
+		t.Fatalf("UnmarshalBinary() = %v want %v", &c, b)
+	}
+	// other min, move the items.
+	d := set.NewBase(200, -200)
+	if err := d.UnmarshalBinary(data); err != nil || d.String() != b.String() {
+		t.Fatalf("UnmarshalBinary() = %v %v want %v", d, err, b)
+	}
+	// items out of range.
+	e := set.NewBase(50, 0)
+	if err := e.UnmarshalBinary(data); !errors.Is(err, set.ErrOverflow) || e.String() != "{40}" {
+		t.Fatalf("UnmarshalBinary() = %v %v", e, err)
+	}
+	var s set.Static
+	if err := s.UnmarshalBinary(data); !errors.Is(err, set.ErrKind) {
+		t.Fatalf("UnmarshalBinary() base to static err = %v", err)
+	}
+}
+
+// TestUnmarshalBinaryMax the set is init with the max of data,
+// the max is kept across a round trip.
+func TestUnmarshalBinaryMax(t *testing.T) {
+	var st set.Static
+	st.OnceInit(2000000)
+	st.Store(5)
+	data, _ := st.MarshalBinary()
+	var s set.Static
+	if err := s.UnmarshalBinary(data); err != nil || s.String() != "{5}" {
+		t.Fatalf("UnmarshalBinary() = %v %v", &s, err)
+	}
+	if !s.Store(2000000) || s.Store(2000001) {
+		t.Fatalf("UnmarshalBinary() max not kept")
+	}
+	b := set.NewBase(3000000, -10)
+	b.Add(-10)
+	data, _ = b.MarshalBinary()
+	var e set.Base
+	if err := e.UnmarshalBinary(data); err != nil || e.String() != "{-10}" {
+		t.Fatalf("UnmarshalBinary() base = %v %v", &e, err)
+	}
+	e.Add(3000000)
+	e.Add(3000100)
+	if !e.Has(3000000) || e.Has(3000100) {
+		t.Fatalf("UnmarshalBinary() base max not kept")
+	}
+}
+
+func TestUnmarshalBinaryErr(t *testing.T) {
+	s := set.NewStatic(1000, 1, 2, 3, 500)
+	data, _ := s.(*set.Static).MarshalBinary()
+	var d set.Static
+	bad := append([]byte(nil), data...)
+	bad[len(bad)-6] ^= 1
+	if err := d.UnmarshalBinary(bad); !errors.Is(err, set.ErrChecksum) {
+		t.Fatalf("checksum err = %v", err)
+	}
+	for _, n := range []int{0, 3, 7, len(data) - 1} {
+		if err := d.UnmarshalBinary(data[:n]); err == nil {
+			t.Fatalf("UnmarshalBinary(data[:%d]) no err", n)
+		}
+	}
+}
+
+func TestGob(t *testing.T) {
+	type record struct {
+		S *set.Static
+		D *set.Dynamic
+		B *set.Base
+	}
+	in := record{
+		S: set.NewStatic(1000, 1, 2, 999).(*set.Static),
+		D: set.NewDynamic(0, 5, 1<<16).(*set.Dynamic),
+		B: set.NewBase(10, -10),
+	}
+	in.B.Add(-10)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	var out record
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !set.Equal(in.S, out.S) || !set.Equal(in.D, out.D) || in.B.String() != out.B.String() {
+		t.Fatalf("gob = %v %v %v", out.S, out.D, out.B)
+	}
+}
@@ -0,0 +1,142 @@
+package set
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// the words of Static are hold in pages of pageWords words,
+// the pages are shared by clones, and copied on the first write.
+const (
+	pageShift = 10
+	pageWords = 1 << pageShift
+	pageMask  = pageWords - 1
+)
+
+// staticPages is the page table of Static.
+type staticPages struct {
+	// 1 if the table is shared by clones, copy it before write.
+	shared uint32
+
+	// number of words of all pages.
+	words int
+
+	// pointer to the first word of each page.
+	pages []unsafe.Pointer
+
+	// owned[j] is 1 if pages[j] is own by this table, write in place.
+	owned []uint32
+}
+
+// newPages return a page table of n words, all pages are owned.
+func newPages(n int) *staticPages {
+	data := make([]uint32, n)
+	t := &staticPages{
+		words: n,
+		pages: make([]unsafe.Pointer, (n+pageMask)>>pageShift),
+		owned: make([]uint32, (n+pageMask)>>pageShift),
+	}
+	for j := range t.pages {
+		t.pages[j] = unsafe.Pointer(&data[j<<pageShift])
+		t.owned[j] = 1
+	}
+	return t
+}
+
+// pageLen return the number of words of page j.
+func (t *staticPages) pageLen(j int) int {
+	return min(pageWords, t.words-j<<pageShift)
+}
+
+// word return the pointer of word i.
+func (t *staticPages) word(i int) *uint32 {
+	p := atomic.LoadPointer(&t.pages[i>>pageShift])
+	return (*uint32)(unsafe.Add(p, (i&pageMask)*4))
+}
+
+func (s *Static) getPages() *staticPages {
+	return (*staticPages)(atomic.LoadPointer(&s.pages))
+}
+
+// writable return the pointer of word i to write,
+// copy the table and the page first if they are shared.
+// must be call in the writer gate,
+// so that no clone take place until the write done.
+func (s *Static) writable(i int) *uint32 {
+	t := s.getPages()
+	j := i >> pageShift
+	if atomic.LoadUint32(&t.shared) == 0 && atomic.LoadUint32(&t.owned[j]) == 1 {
+		return t.word(i)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t = s.getPages()
+	if atomic.LoadUint32(&t.shared) == 1 {
+		// copy the table, the pages are still shared.
+		nt := &staticPages{
+			words: t.words,
+			pages: make([]unsafe.Pointer, len(t.pages)),
+			owned: make([]uint32, len(t.owned)),
+		}
+		for k := range t.pages {
+			nt.pages[k] = atomic.LoadPointer(&t.pages[k])
+		}
+		atomic.StorePointer(&s.pages, unsafe.Pointer(nt))
+		t = nt
+	}
+	if atomic.LoadUint32(&t.owned[j]) == 0 {
+		// no one write the shared page, copy it without atomic.
+		n := t.pageLen(j)
+		data := make([]uint32, n)
+		copy(data, unsafe.Slice((*uint32)(atomic.LoadPointer(&t.pages[j])), n))
+		atomic.StorePointer(&t.pages[j], unsafe.Pointer(&data[0]))
+		atomic.StoreUint32(&t.owned[j], 1)
+	}
+	return t.word(i)
+}
+
+// Clone return a copy of the set in O(1),
+// the pages of words are shared, and copied lazily on the first write of
+// either set, so the clones can be written concurrently.
+// Clone close the writer gate of Snapshot while sharing the pages,
+// the first Clone of a set also stop the world once as Snapshot,
+// the writers of both sets pass the gate from now on.
+// the rank index is not cloned.
+// time complexity: O(1)
+func (s *Static) Clone() *Static {
+	var p Static
+	s.cloneTo(&p)
+	return &p
+}
+
+// cloneTo init p as a clone of s.
+func (s *Static) cloneTo(p *Static) {
+	s.onceInit(initSize)
+	gates := s.lockGate()
+	t := s.getPages()
+	atomic.StoreUint32(&t.shared, 1)
+	p.once.Do(func() {
+		p.max = s.getMax()
+		p.cap = s.getCap()
+		p.len = s.getLen()
+		p.count = atomic.LoadUint32(&s.count)
+		p.pages = unsafe.Pointer(t)
+		// the table is shared, the writers of p copy it in the gate.
+		p.gates = unsafe.Pointer(&snapGates{})
+	})
+	s.unlockGate(gates)
+}
+
+// Clone return a copy of the set in O(1),
+// the pages of words are shared, and copied lazily on the first write.
+// time complexity: O(1)
+func (s *Base) Clone() *Base {
+	p := &Base{}
+	p.once.Do(func() {
+		p.max = atomic.LoadInt32(&s.max)
+		p.min = atomic.LoadInt32(&s.min)
+		p.cap = atomic.LoadInt32(&s.cap)
+	})
+	s.Static.cloneTo(&p.Static)
+	return p
+}
@@ -0,0 +1,154 @@
+package set_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+func TestClone(t *testing.T) {
+	const max = 1 << 20
+	var s set.Static
+	s.OnceInit(max)
+	for i := uint32(0); i <= max; i += 1000 {
+		s.Store(i)
+	}
+	c := s.Clone()
+	if !set.Equal(&s, c) || set.Size(c) != set.Size(&s) {
+		t.Fatalf("Clone() = %d items want %d", set.Size(c), set.Size(&s))
+	}
+	want := set.Copy(&s)
+
+	// write either set, the other not change.
+	c.Store(1)
+	c.Delete(0)
+	s.Store(2)
+	s.AddRange(500001, 500999)
+	if s.Load(1) || !s.Load(0) || c.Load(2) || !c.Load(1) || c.Load(500001) {
+		t.Fatalf("Clone() shared write")
+	}
+	if !c.Store(max) || c.Store(max+1) {
+		t.Fatalf("Clone() max err")
+	}
+	c.Delete(max)
+	cc := c.Clone()
+	s.Delete(2)
+	s.RemoveRange(500001, 500999)
+	if !set.Equal(&s, want) {
+		t.Fatalf("Clone() origin changed")
+	}
+	c.Store(0)
+	c.Delete(1)
+	if !set.Equal(c, want) || !cc.Load(1) || cc.Load(0) {
+		t.Fatalf("Clone() of clone err")
+	}
+	if u := set.Union(c, cc); set.Size(u) != set.Size(want)+1 {
+		t.Fatalf("Union(Clone()) = %d", set.Size(u))
+	}
+
+	var empty set.Static
+	if e := empty.Clone(); !e.Store(5) || empty.Load(5) {
+		t.Fatalf("Clone() empty err")
+	}
+}
+
+func TestCloneBase(t *testing.T) {
+	b := set.NewBase(100, -100)
+	b.Add(-100)
+	b.Add(7)
+	c := b.Clone()
+	c.Add(-5)
+	b.Remove(7)
+	if c.String() != "{-100 -5 7}" || b.String() != "{-100}" {
+		t.Fatalf("Clone() = %v, origin %v", c, b)
+	}
+}
+
+func TestCloneAlloc(t *testing.T) {
+	var s set.Static
+	s.OnceInit(1 << 26)
+	s.Store(1)
+	var m0, m1 runtime.MemStats
+	runtime.ReadMemStats(&m0)
+	c := s.Clone()
+	c.Store(2)
+	runtime.ReadMemStats(&m1)
+	// only the page table and one page are copied.
+	if n := m1.TotalAlloc - m0.TotalAlloc; n > 128<<10 {
+		t.Fatalf("Clone() alloc %d bytes", n)
+	}
+	if !c.Load(1) || !c.Load(2) || s.Load(2) {
+		t.Fatalf("Clone() = %v", c)
+	}
+}
+
+// TestCloneNoOp the writes not change a word not copy the shared page.
+func TestCloneNoOp(t *testing.T) {
+	const max = 1 << 26
+	var s set.Static
+	s.OnceInit(max)
+	s.Store(1)
+	c := s.Clone()
+	var m0, m1 runtime.MemStats
+	runtime.ReadMemStats(&m0)
+	c.IntersectWith(&s)
+	c.RemoveRange(1<<20, max)
+	c.AddRange(1, 1)
+	c.Delete(100)
+	set.Clear(c.Clone())
+	runtime.ReadMemStats(&m1)
+	if n := m1.TotalAlloc - m0.TotalAlloc; n > 64<<10 {
+		t.Fatalf("no-op write of Clone() alloc %d bytes", n)
+	}
+	if !c.Load(1) || set.Size(c) != 1 {
+		t.Fatalf("Clone() = %v", c)
+	}
+}
+
+// TestCloneConcurrent write the origin and clones concurrently,
+// while clone the origin again and again,
+// no write is lost or leak to the other set.
+func TestCloneConcurrent(t *testing.T) {
+	const max = 1 << 16
+	var s set.Static
+	s.OnceInit(max)
+	c := s.Clone()
+	var wg sync.WaitGroup
+	for g := uint32(0); g < 4; g++ {
+		wg.Add(2)
+		go func(g uint32) {
+			defer wg.Done()
+			for i := g; i <= max; i += 8 {
+				s.Store(i)
+			}
+		}(g)
+		go func(g uint32) {
+			defer wg.Done()
+			for i := g + 4; i <= max; i += 8 {
+				c.Store(i)
+			}
+		}(g)
+	}
+	var clones []*set.Static
+	for i := 0; i < 20; i++ {
+		clones = append(clones, s.Clone())
+		runtime.Gosched()
+	}
+	wg.Wait()
+	for i := uint32(0); i <= max; i++ {
+		if s.Load(i) != (i%8 < 4) || c.Load(i) != (i%8 >= 4) {
+			t.Fatalf("Clone() concurrent write %d lost or leak", i)
+		}
+	}
+	// each clone is a subset of the origin, taken at some time.
+	for _, p := range clones {
+		if !set.IsSubset(p, &s) {
+			t.Fatalf("Clone() concurrent not subset")
+		}
+		if n := len(set.Items(p)); set.Size(p) != n {
+			t.Fatalf("Clone() concurrent Size() = %d want %d", set.Size(p), n)
+		}
+	}
+}
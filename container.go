@@ -0,0 +1,559 @@
+package set
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	// array container max cardinality,
+	// bigger than it use bitmap container.
+	arrayMaxSize = 4096
+
+	// bitmap container words, 1<<16 bits.
+	bitmapWords = 1 << 10
+
+	// bitmap container size in bytes.
+	bitmapBytes = bitmapWords * 8
+)
+
+// container hold the low 16 bits of items in one chunk of Roaring.
+//
+// container is not concurrent safe, Roaring protect it.
+type container interface {
+	// contains report whether x in container.
+	contains(x uint16) bool
+
+	// add x to container,
+	// return the container may be converted and whether x is new.
+	add(x uint16) (container, bool)
+
+	// remove x from container,
+	// return the container may be converted and whether x was in.
+	remove(x uint16) (container, bool)
+
+	// card return the number of items in container.
+	card() int
+
+	// iterate calls f with hb|x for each item in ascending order.
+	// return false if f returns false.
+	iterate(hb uint32, f func(x uint32) bool) bool
+
+	// words return a bitmap view of the container,
+	// the result must not be changed.
+	words() *[bitmapWords]uint64
+
+	// clone return a deep copy of the container.
+	clone() container
+
+	// sizeInBytes return the serialized payload size.
+	sizeInBytes() int
+}
+
+// arrayContainer sorted low 16 bits, use for sparse chunk.
+type arrayContainer struct {
+	content []uint16
+}
+
+func newArrayContainer(cap int) *arrayContainer {
+	return &arrayContainer{content: make([]uint16, 0, cap)}
+}
+
+func (c *arrayContainer) search(x uint16) int {
+	return sort.Search(len(c.content), func(i int) bool { return c.content[i] >= x })
+}
+
+func (c *arrayContainer) contains(x uint16) bool {
+	i := c.search(x)
+	return i < len(c.content) && c.content[i] == x
+}
+
+func (c *arrayContainer) add(x uint16) (container, bool) {
+	i := c.search(x)
+	if i < len(c.content) && c.content[i] == x {
+		return c, false
+	}
+	if len(c.content) >= arrayMaxSize {
+		b := c.toBitmap()
+		b.set(x)
+		return b, true
+	}
+	c.content = append(c.content, 0)
+	copy(c.content[i+1:], c.content[i:])
+	c.content[i] = x
+	return c, true
+}
+
+func (c *arrayContainer) remove(x uint16) (container, bool) {
+	i := c.search(x)
+	if i >= len(c.content) || c.content[i] != x {
+		return c, false
+	}
+	c.content = append(c.content[:i], c.content[i+1:]...)
+	return c, true
+}
+
+func (c *arrayContainer) card() int { return len(c.content) }
+
+func (c *arrayContainer) iterate(hb uint32, f func(x uint32) bool) bool {
+	for _, x := range c.content {
+		if !f(hb | uint32(x)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *arrayContainer) words() *[bitmapWords]uint64 { return &c.toBitmap().bits }
+
+func (c *arrayContainer) clone() container {
+	n := newArrayContainer(len(c.content))
+	n.content = append(n.content, c.content...)
+	return n
+}
+
+func (c *arrayContainer) sizeInBytes() int { return 2 * len(c.content) }
+
+func (c *arrayContainer) toBitmap() *bitmapContainer {
+	b := new(bitmapContainer)
+	for _, x := range c.content {
+		b.set(x)
+	}
+	return b
+}
+
+// bitmapContainer 1<<16 bits, use for dense chunk.
+type bitmapContainer struct {
+	// number of item in bits
+	n    int
+	bits [bitmapWords]uint64
+}
+
+func (c *bitmapContainer) set(x uint16) bool {
+	idx, mod := x>>6, x&63
+	if c.bits[idx]&(1<<mod) != 0 {
+		return false
+	}
+	c.bits[idx] |= 1 << mod
+	c.n += 1
+	return true
+}
+
+func (c *bitmapContainer) clear(x uint16) bool {
+	idx, mod := x>>6, x&63
+	if c.bits[idx]&(1<<mod) == 0 {
+		return false
+	}
+	c.bits[idx] &^= 1 << mod
+	c.n -= 1
+	return true
+}
+
+func (c *bitmapContainer) contains(x uint16) bool {
+	return c.bits[x>>6]&(1<<(x&63)) != 0
+}
+
+func (c *bitmapContainer) add(x uint16) (container, bool) {
+	return c, c.set(x)
+}
+
+func (c *bitmapContainer) remove(x uint16) (container, bool) {
+	if !c.clear(x) {
+		return c, false
+	}
+	if c.n <= arrayMaxSize {
+		return c.toArray(), true
+	}
+	return c, true
+}
+
+func (c *bitmapContainer) card() int { return c.n }
+
+func (c *bitmapContainer) iterate(hb uint32, f func(x uint32) bool) bool {
+	for i, w := range c.bits {
+		for w != 0 {
+			j := bits.TrailingZeros64(w)
+			if !f(hb | uint32(i<<6+j)) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+	return true
+}
+
+func (c *bitmapContainer) words() *[bitmapWords]uint64 { return &c.bits }
+
+func (c *bitmapContainer) clone() container {
+	n := *c
+	return &n
+}
+
+func (c *bitmapContainer) sizeInBytes() int { return bitmapBytes }
+
+// recount update n with popcount of bits.
+func (c *bitmapContainer) recount() {
+	c.n = 0
+	for _, w := range c.bits {
+		c.n += bits.OnesCount64(w)
+	}
+}
+
+func (c *bitmapContainer) toArray() *arrayContainer {
+	a := newArrayContainer(c.n)
+	c.iterate(0, func(x uint32) bool {
+		a.content = append(a.content, uint16(x))
+		return true
+	})
+	return a
+}
+
+// interval16 a run of items [start,last].
+type interval16 struct {
+	start uint16
+	last  uint16
+}
+
+// runContainer sorted disjoint intervals, use for clustered chunk.
+type runContainer struct {
+	runs []interval16
+}
+
+// search return the index of the first run with start>x.
+func (c *runContainer) search(x uint16) int {
+	return sort.Search(len(c.runs), func(i int) bool { return c.runs[i].start > x })
+}
+
+func (c *runContainer) contains(x uint16) bool {
+	i := c.search(x)
+	return i > 0 && c.runs[i-1].last >= x
+}
+
+func (c *runContainer) add(x uint16) (container, bool) {
+	i := c.search(x)
+	if i > 0 && c.runs[i-1].last >= x {
+		return c, false
+	}
+	// x follow the previous run
+	joinPrev := i > 0 && c.runs[i-1].last+1 == x
+	// x precede the next run
+	joinNext := i < len(c.runs) && x+1 == c.runs[i].start
+	switch {
+	case joinPrev && joinNext:
+		c.runs[i-1].last = c.runs[i].last
+		c.runs = append(c.runs[:i], c.runs[i+1:]...)
+	case joinPrev:
+		c.runs[i-1].last = x
+	case joinNext:
+		c.runs[i].start = x
+	default:
+		c.runs = append(c.runs, interval16{})
+		copy(c.runs[i+1:], c.runs[i:])
+		c.runs[i] = interval16{start: x, last: x}
+	}
+	return c.repair(), true
+}
+
+func (c *runContainer) remove(x uint16) (container, bool) {
+	i := c.search(x)
+	if i == 0 || c.runs[i-1].last < x {
+		return c, false
+	}
+	r := &c.runs[i-1]
+	switch {
+	case r.start == x && r.last == x:
+		c.runs = append(c.runs[:i-1], c.runs[i:]...)
+	case r.start == x:
+		r.start += 1
+	case r.last == x:
+		r.last -= 1
+	default:
+		// split [start,last] to [start,x-1] [x+1,last]
+		next := interval16{start: x + 1, last: r.last}
+		r.last = x - 1
+		c.runs = append(c.runs, interval16{})
+		copy(c.runs[i+1:], c.runs[i:])
+		c.runs[i] = next
+	}
+	return c.repair(), true
+}
+
+func (c *runContainer) card() int {
+	n := 0
+	for _, r := range c.runs {
+		n += int(r.last-r.start) + 1
+	}
+	return n
+}
+
+func (c *runContainer) iterate(hb uint32, f func(x uint32) bool) bool {
+	for _, r := range c.runs {
+		for x := uint32(r.start); x <= uint32(r.last); x++ {
+			if !f(hb | x) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *runContainer) words() *[bitmapWords]uint64 { return &c.toBitmap().bits }
+
+func (c *runContainer) clone() container {
+	n := &runContainer{runs: make([]interval16, len(c.runs))}
+	copy(n.runs, c.runs)
+	return n
+}
+
+func (c *runContainer) sizeInBytes() int { return 2 + 4*len(c.runs) }
+
+// repair convert c to other container if it's smaller.
+func (c *runContainer) repair() container {
+	if c.sizeInBytes() <= bitmapBytes {
+		return c
+	}
+	return optimize(c)
+}
+
+func (c *runContainer) toBitmap() *bitmapContainer {
+	b := new(bitmapContainer)
+	for _, r := range c.runs {
+		setRange64(b.bits[:], uint32(r.start), uint32(r.last))
+	}
+	b.recount()
+	return b
+}
+
+// setRange64 set bits [lo,hi] in words.
+func setRange64(words []uint64, lo, hi uint32) {
+	for lo <= hi {
+		idx, mod := lo>>6, lo&63
+		end := idx<<6 + 63
+		if end > hi {
+			end = hi
+		}
+		n := end - lo + 1
+		if n == 64 {
+			words[idx] = ^uint64(0)
+		} else {
+			words[idx] |= (1<<n - 1) << mod
+		}
+		lo = end + 1
+	}
+}
+
+// toRuns build a run container from c.
+func toRuns(c container) *runContainer {
+	if r, ok := c.(*runContainer); ok {
+		return r
+	}
+	rc := &runContainer{}
+	c.iterate(0, func(x uint32) bool {
+		n := len(rc.runs)
+		if n > 0 && uint32(rc.runs[n-1].last)+1 == x {
+			rc.runs[n-1].last = uint16(x)
+		} else {
+			rc.runs = append(rc.runs, interval16{start: uint16(x), last: uint16(x)})
+		}
+		return true
+	})
+	return rc
+}
+
+// numRuns return the number of runs in c.
+func numRuns(c container) int {
+	if r, ok := c.(*runContainer); ok {
+		return len(r.runs)
+	}
+	n := 0
+	next := int64(-1)
+	c.iterate(0, func(x uint32) bool {
+		if int64(x) != next {
+			n += 1
+		}
+		next = int64(x) + 1
+		return true
+	})
+	return n
+}
+
+// optimize return the smallest container of c.
+func optimize(c container) container {
+	card := c.card()
+	runSize := 2 + 4*numRuns(c)
+	arraySize := 2 * card
+	if card > arrayMaxSize {
+		arraySize = bitmapBytes + 1
+	}
+	switch {
+	case runSize < arraySize && runSize < bitmapBytes:
+		return toRuns(c)
+	case card <= arrayMaxSize:
+		if a, ok := c.(*arrayContainer); ok {
+			return a
+		}
+		a := newArrayContainer(card)
+		c.iterate(0, func(x uint32) bool {
+			a.content = append(a.content, uint16(x))
+			return true
+		})
+		return a
+	default:
+		if b, ok := c.(*bitmapContainer); ok {
+			return b
+		}
+		b := &bitmapContainer{bits: *c.words()}
+		b.recount()
+		return b
+	}
+}
+
+// fromWords return an array or bitmap container of words,
+// or nil if words is empty.
+func fromWords(words *[bitmapWords]uint64) container {
+	b := &bitmapContainer{bits: *words}
+	b.recount()
+	if b.n == 0 {
+		return nil
+	}
+	if b.n <= arrayMaxSize {
+		return b.toArray()
+	}
+	return b
+}
+
+// containerAnd return the intersection of a and b, nil if empty.
+func containerAnd(a, b container) container {
+	aa, aok := a.(*arrayContainer)
+	ba, bok := b.(*arrayContainer)
+	switch {
+	case aok && bok:
+		n := newArrayContainer(min(len(aa.content), len(ba.content)))
+		i, j := 0, 0
+		for i < len(aa.content) && j < len(ba.content) {
+			x, y := aa.content[i], ba.content[j]
+			switch {
+			case x == y:
+				n.content = append(n.content, x)
+				i += 1
+				j += 1
+			case x < y:
+				i += 1
+			default:
+				j += 1
+			}
+		}
+		return nonEmpty(n)
+	case aok:
+		return arrayFilter(aa, b, true)
+	case bok:
+		return arrayFilter(ba, a, true)
+	}
+	var w [bitmapWords]uint64
+	aw, bw := a.words(), b.words()
+	for i := range w {
+		w[i] = aw[i] & bw[i]
+	}
+	return fromWords(&w)
+}
+
+// containerOr return the union of a and b.
+func containerOr(a, b container) container {
+	aa, aok := a.(*arrayContainer)
+	ba, bok := b.(*arrayContainer)
+	if aok && bok && len(aa.content)+len(ba.content) <= arrayMaxSize {
+		n := newArrayContainer(len(aa.content) + len(ba.content))
+		i, j := 0, 0
+		for i < len(aa.content) && j < len(ba.content) {
+			x, y := aa.content[i], ba.content[j]
+			switch {
+			case x == y:
+				n.content = append(n.content, x)
+				i += 1
+				j += 1
+			case x < y:
+				n.content = append(n.content, x)
+				i += 1
+			default:
+				n.content = append(n.content, y)
+				j += 1
+			}
+		}
+		n.content = append(n.content, aa.content[i:]...)
+		n.content = append(n.content, ba.content[j:]...)
+		return n
+	}
+	var w [bitmapWords]uint64
+	aw, bw := a.words(), b.words()
+	for i := range w {
+		w[i] = aw[i] | bw[i]
+	}
+	return fromWords(&w)
+}
+
+// containerAndNot return the items in a but not in b, nil if empty.
+func containerAndNot(a, b container) container {
+	if aa, ok := a.(*arrayContainer); ok {
+		return arrayFilter(aa, b, false)
+	}
+	var w [bitmapWords]uint64
+	aw := a.words()
+	if ba, ok := b.(*arrayContainer); ok {
+		w = *aw
+		for _, x := range ba.content {
+			w[x>>6] &^= 1 << (x & 63)
+		}
+		return fromWords(&w)
+	}
+	bw := b.words()
+	for i := range w {
+		w[i] = aw[i] &^ bw[i]
+	}
+	return fromWords(&w)
+}
+
+// containerXor return the items in a or in b but not both, nil if empty.
+func containerXor(a, b container) container {
+	var w [bitmapWords]uint64
+	aw, bw := a.words(), b.words()
+	for i := range w {
+		w[i] = aw[i] ^ bw[i]
+	}
+	return fromWords(&w)
+}
+
+// containerEqual report whether a and b hold the same items.
+func containerEqual(a, b container) bool {
+	if a.card() != b.card() {
+		return false
+	}
+	aa, aok := a.(*arrayContainer)
+	ba, bok := b.(*arrayContainer)
+	if aok && bok {
+		for i := range aa.content {
+			if aa.content[i] != ba.content[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return *a.words() == *b.words()
+}
+
+// arrayFilter return the items in a which b contains or not.
+func arrayFilter(a *arrayContainer, b container, in bool) container {
+	n := newArrayContainer(len(a.content))
+	for _, x := range a.content {
+		if b.contains(x) == in {
+			n.content = append(n.content, x)
+		}
+	}
+	return nonEmpty(n)
+}
+
+func nonEmpty(a *arrayContainer) container {
+	if len(a.content) == 0 {
+		return nil
+	}
+	return a
+}
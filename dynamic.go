@@ -1,6 +1,7 @@
 package set
 
 import (
+	"math/bits"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -18,6 +19,9 @@ type Dynamic struct {
 
 	// *baseEntry
 	node unsafe.Pointer
+
+	// 1 if rank index enable, each node build it's own index.
+	ranked uint32
 }
 
 func (s *Dynamic) init(max int) {
@@ -40,6 +44,7 @@ func (s *Dynamic) onceInit(max int) { s.init(max) }
 func (s *Dynamic) OnceInit(max int) { s.init(max) }
 
 func (s *Dynamic) getMax() uint32    { return atomic.LoadUint32(&s.max) }
+func (s *Dynamic) wordBits() uint32  { return 16 }
 func (s *Dynamic) getCap() uint32    { return s.getEntry().getCap() }
 func (s *Dynamic) getLen() uint32    { return s.getEntry().getLen() }
 func (s *Dynamic) load(i int) uint32 { return s.getEntry().load(i) }
@@ -106,6 +111,47 @@ func (s *Dynamic) LoadOrStore(x uint32) (loaded, ok bool) {
 	}
 }
 
+// StoreMany adds the items xs to the set.
+// the items of a word are build locally, and publish with one CAS,
+// so sorted xs only cost one atomic op per word.
+// return false if some x overflow bigger than max, the others still stored.
+// time complexity: O(len(xs))
+func (s *Dynamic) StoreMany(xs []uint32) bool {
+	s.OnceInit(0)
+	max := s.getMax()
+	ok := true
+	idx, item := -1, uint32(0)
+	for _, x := range xs {
+		if x > max {
+			ok = false
+			continue
+		}
+		i, mod := int(x>>4), x&15
+		if i != idx {
+			s.orWord(idx, item)
+			idx, item = i, 0
+		}
+		item |= 1 << mod
+	}
+	s.orWord(idx, item)
+	return ok
+}
+
+// orWord set the bits of item in word i,
+// grow the set if i overflow.
+func (s *Dynamic) orWord(i int, item uint32) {
+	if item == 0 {
+		return
+	}
+	for {
+		e := s.getEntry()
+		if !e.overflow(uint32(i)) && e.modify(i, func(old uint32) uint32 { return old | item }) {
+			return
+		}
+		dynGrowWork(s, e, uint32(i)+1)
+	}
+}
+
 // Delete remove x from the set
 // return true if success, false if x overflow
 // time complexity: O(1)
@@ -140,6 +186,7 @@ func (s *Dynamic) LoadAndDelete(x uint32) (loaded, ok bool) {
 // Range does not necessarily correspond to any consistent snapshot of the set's
 // contents: no item will be visited more than once, but if the item
 // is stored or deleted concurrently, Range may reflect any mapping for that item.
+// use Snapshot for a consistent view.
 //
 // Range may be O(N) with the worst time complexity.
 // example set:
@@ -149,12 +196,240 @@ func (s *Dynamic) Range(f func(x uint32) bool) {
 	e.walk(f)
 }
 
+// RangeWords calls f sequentially for each 64 items word of the set,
+// base is the first item of word, bit j of word is item base+j.
+// the empty words are skipped.
+// If f returns false, range stops the iteration.
+// time complexity: O(N/64)
+func (s *Dynamic) RangeWords(f func(base uint32, word uint64) bool) {
+	walkWords(s.getEntry(), f)
+}
+
+// RangeFrom calls f sequentially for each item >= lo in the set.
+// If f returns false, range stops the iteration.
+func (s *Dynamic) RangeFrom(lo uint32, f func(x uint32) bool) {
+	walkBetween(s.getEntry(), lo, s.getMax(), f)
+}
+
+// RangeBetween calls f sequentially for each item in [lo,hi] of the set.
+// it seek to the word hold lo, not scan from 0.
+// If f returns false, range stops the iteration.
+//
+// RangeBetween does not necessarily correspond to any consistent snapshot of the set's
+// contents, the same as Range.
+// time complexity: O((hi-lo)/16)
+func (s *Dynamic) RangeBetween(lo, hi uint32, f func(x uint32) bool) {
+	walkBetween(s.getEntry(), lo, hi, f)
+}
+
+// RangeReverse calls f sequentially for each item in the set,
+// from the biggest to the smallest.
+// If f returns false, range stops the iteration.
+// time complexity: O(N/16)
+func (s *Dynamic) RangeReverse(f func(x uint32) bool) {
+	walkReverse(s.getEntry(), 0, s.getMax(), f)
+}
+
+// RangeReverseBetween calls f sequentially for each item in [lo,hi] of the set,
+// from hi to lo.
+// If f returns false, range stops the iteration.
+// time complexity: O((hi-lo)/16)
+func (s *Dynamic) RangeReverseBetween(lo, hi uint32, f func(x uint32) bool) {
+	walkReverse(s.getEntry(), lo, hi, f)
+}
+
+// Min return the smallest item in the set.
+// ok is false if the set is empty.
+// time complexity: O(N/16)
+func (s *Dynamic) Min() (x uint32, ok bool) {
+	return nextBit(s.getEntry(), 0)
+}
+
+// Max return the biggest item in the set.
+// ok is false if the set is empty.
+// time complexity: O(N/16)
+func (s *Dynamic) Max() (x uint32, ok bool) {
+	return prevBit(s.getEntry(), s.getMax())
+}
+
+// Next return the smallest item in the set at or after x.
+// ok is false if no such item.
+// time complexity: O(N/16)
+func (s *Dynamic) Next(x uint32) (next uint32, ok bool) {
+	if x > s.getMax() {
+		return 0, false
+	}
+	return nextBit(s.getEntry(), x)
+}
+
+// Prev return the biggest item in the set at or before x.
+// ok is false if no such item.
+// time complexity: O(N/16)
+func (s *Dynamic) Prev(x uint32) (prev uint32, ok bool) {
+	return prevBit(s.getEntry(), x)
+}
+
+// AddRange adds all items in [lo,hi] to the set.
+// the set grow once to hold hi.
+// return false if hi overflow bigger than max.
+// time complexity: O((hi-lo)/16)
+func (s *Dynamic) AddRange(lo, hi uint32) bool {
+	return s.updateRange(lo, hi, opAdd, true)
+}
+
+// RemoveRange remove all items in [lo,hi] from the set.
+// return false if hi overflow bigger than max.
+// time complexity: O((hi-lo)/16)
+func (s *Dynamic) RemoveRange(lo, hi uint32) bool {
+	return s.updateRange(lo, hi, opRemove, false)
+}
+
+// FlipRange toggle all items in [lo,hi],
+// item in set is removed, item not in set is added.
+// the set grow once to hold hi.
+// return false if hi overflow bigger than max.
+// time complexity: O((hi-lo)/16)
+func (s *Dynamic) FlipRange(lo, hi uint32) bool {
+	return s.updateRange(lo, hi, opFlip, true)
+}
+
+// updateRange set each word in [lo,hi] to op(item,mask) with CAS.
+// grow the set once to hold hi if grow.
+func (s *Dynamic) updateRange(lo, hi uint32, op func(item, mask uint32) uint32, grow bool) bool {
+	s.OnceInit(0)
+	if hi > s.getMax() {
+		return false
+	}
+	if lo > hi {
+		return true
+	}
+	from, to := int(lo>>4), int(hi>>4)
+	if !grow {
+		to = min(to, int(s.getLen())-1)
+	}
+	s.updateWords(from, to, func(idx int, item uint32) uint32 {
+		return op(item, rangeMask(16, lo, hi, idx))
+	})
+	return true
+}
+
+// updateWords set each word idx in [from,to] to f(idx,item) with CAS,
+// the items bigger than max are dropped.
+// the set grow once to hold word to,
+// if a word is frozen by growing, continue from it in the new node.
+func (s *Dynamic) updateWords(from, to int, f func(idx int, item uint32) uint32) {
+	s.OnceInit(0)
+	max := s.getMax()
+	if last := int(max >> 4); to > last {
+		to = last
+	}
+	for from <= to {
+		e := s.getEntry()
+		if e.overflow(uint32(to)) {
+			dynGrowWork(s, e, uint32(to)+1)
+			continue
+		}
+		for ; from <= to; from++ {
+			mask := validMask(16, max, from)
+			if !e.modify(from, func(item uint32) uint32 { return f(from, item) & mask }) {
+				break
+			}
+		}
+	}
+}
+
+// Not return a new set of the items in [0,max] but not in s.
+// if s has no max, the universe is [0,maximum] whatever s has allocated,
+// so equal sets have equal complements, the result allocate about 128MB,
+// give s a max if the set is not so big.
+// time complexity: O(max/16)
+func (s *Dynamic) Not() *Dynamic {
+	p := newWordSet(true, s.getMax()).(*Dynamic)
+	notInto(p, s.getEntry(), s.getMax())
+	return p
+}
+
+// UnionWith set s to the union set of s and t in place.
+// each word of s is update with CAS.
+// time complexity: O(N/16)
+func (s *Dynamic) UnionWith(t Set) { withOperation(s, t, opUnion) }
+
+// IntersectWith set s to the intersection set of s and t in place.
+// each word of s is update with CAS.
+// time complexity: O(N/16)
+func (s *Dynamic) IntersectWith(t Set) { withOperation(s, t, opIntersect) }
+
+// DifferenceWith remove the items in t from s in place.
+// each word of s is update with CAS.
+// time complexity: O(N/16)
+func (s *Dynamic) DifferenceWith(t Set) { withOperation(s, t, opDifference) }
+
+// SymmetricDifferenceWith set s to the items in s or in t but not both in place.
+// each word of s is update with CAS.
+// time complexity: O(N/16)
+func (s *Dynamic) SymmetricDifferenceWith(t Set) { withOperation(s, t, opComplement) }
+
+// EnableRank build a rank index of blocks item count,
+// make Rank, Select and CountRange O(log N).
+// the index is kept by Store and Delete once enable,
+// and rebuild when the set grow.
+//
+// EnableRank scan the set without stop writers,
+// call it before the set is shared between goroutines.
+func (s *Dynamic) EnableRank() {
+	atomic.StoreUint32(&s.ranked, 1)
+	for {
+		e := s.getEntry()
+		if e.getRank() == nil {
+			atomic.CompareAndSwapPointer(&e.rank, nil, unsafe.Pointer(newRankIndex(e)))
+		}
+		if e == s.getEntry() {
+			return
+		}
+	}
+}
+
+// Rank return the number of items in the set which <= x.
+// time complexity: O(N/16), O(log N) with rank index.
+func (s *Dynamic) Rank(x uint32) int {
+	e := s.getEntry()
+	return rankBit(e, e.getRank(), x)
+}
+
+// Select return the k-th smallest item in the set, k start from 0.
+// ok is false if k >= the number of items.
+// time complexity: O(N/16), O(log N) with rank index.
+func (s *Dynamic) Select(k int) (x uint32, ok bool) {
+	e := s.getEntry()
+	return selectBit(e, e.getRank(), k)
+}
+
+// CountRange return the number of items in the set which in [lo,hi].
+// time complexity: O((hi-lo)/16), O(log N) with rank index.
+func (s *Dynamic) CountRange(lo, hi uint32) int {
+	if lo > hi {
+		return 0
+	}
+	e := s.getEntry()
+	if r := e.getRank(); r != nil {
+		n := rankBit(e, r, hi)
+		if lo > 0 {
+			n -= rankBit(e, r, lo-1)
+		}
+		return n
+	}
+	return countBit(e, lo, hi)
+}
+
 type dynEntry struct {
 	resize uint32
 	count  uint32   // number of element in dynEntry
 	len    uint32   // len(data)
 	cap    uint32   // cap(data)
 	data   []uint32 // when evacuting,can't store nor delete.
+
+	// *rankIndex, nil if rank index not enable.
+	rank unsafe.Pointer
 }
 
 func newNode(max uint32) *dynEntry {
@@ -170,15 +445,34 @@ func (e *dynEntry) getMax() uint32 { return e.getCap() << 4 }
 func (e *dynEntry) getLen() uint32 { return atomic.LoadUint32(&e.len) }
 func (e *dynEntry) getCap() uint32 { return atomic.LoadUint32(&e.cap) }
 
-// load data[i]
-func (e *dynEntry) load(i int) uint32 { return atomic.LoadUint32(&e.data[i]) }
+func (e *dynEntry) wordBits() uint32 { return 16 }
+
+// load data[i] without freezeBit
+func (e *dynEntry) load(i int) uint32 { return atomic.LoadUint32(&e.data[i]) &^ freezeBit }
 
 // store data[i]=val
 func (e *dynEntry) store(i int, val uint32) {
 	if e.overflow(uint32(i)) {
 		return
 	}
-	atomic.StoreUint32(&e.data[i], val)
+	old := atomic.SwapUint32(&e.data[i], val) &^ freezeBit
+	e.account(i, bits.OnesCount32(val)-bits.OnesCount32(old))
+}
+
+// account add delta to count and rank index,
+// after data[i] changed.
+func (e *dynEntry) account(i int, delta int) {
+	if delta == 0 {
+		return
+	}
+	atomic.AddUint32(&e.count, uint32(delta))
+	if r := e.getRank(); r != nil {
+		r.add(i, delta)
+	}
+}
+
+func (e *dynEntry) getRank() *rankIndex {
+	return (*rankIndex)(atomic.LoadPointer(&e.rank))
 }
 
 func (e *dynEntry) tryLoad(idx, mod uint32) (ok bool) {
@@ -186,50 +480,70 @@ func (e *dynEntry) tryLoad(idx, mod uint32) (ok bool) {
 	return (item>>mod)&1 == 1
 }
 
+// tryStore set bit mod of data[idx],
+// ok is false if data[idx] is frozen by growing.
 func (e *dynEntry) tryStore(idx, mod uint32) (loaded, ok bool) {
 	for {
 		item := atomic.LoadUint32(&e.data[idx])
+		if item&freezeBit != 0 {
+			return false, false
+		}
 		if (item>>mod)&1 == 1 {
 			return true, true
 		}
 		if atomic.CompareAndSwapUint32(&e.data[idx], item, item|(1<<mod)) {
-			atomic.AddUint32(&e.count, 1)
+			e.account(int(idx), 1)
 			return false, true
 		}
 	}
 }
 
+// tryDelete clear bit mod of data[idx],
+// ok is false if data[idx] is frozen by growing.
 func (e *dynEntry) tryDelete(idx, mod uint32) (loaded, ok bool) {
 	for {
 		item := atomic.LoadUint32(&e.data[idx])
+		if item&freezeBit != 0 {
+			return false, false
+		}
 		if (item>>mod)&1 == 0 {
 			return false, true
 		}
 		if atomic.CompareAndSwapUint32(&e.data[idx], item, item&^(1<<mod)) {
-			atomic.AddUint32(&e.count, ^uint32(0))
+			e.account(int(idx), -1)
 			return true, true
 		}
 	}
 }
 
-func (e *dynEntry) walk(f func(x uint32) bool) {
-	sLen := e.getLen()
-	for i := 0; i < int(sLen); i++ {
-		item := e.load(i)
-		if item == 0 {
-			continue
+// modify set data[i] to f(data[i]) with CAS,
+// keep count and rank index.
+// return false if data[i] is frozen by growing.
+func (e *dynEntry) modify(i int, f func(item uint32) uint32) bool {
+	for {
+		item := atomic.LoadUint32(&e.data[i])
+		if item&freezeBit != 0 {
+			return false
 		}
-		// TODO add to valid bit
-		for j := 0; j < 16; j++ {
-			if item == 0 {
-				break
-			}
-			if item&1 == 1 {
-				if !f(uint32(i<<4 + j)) {
-					return
-				}
+		n := f(item)
+		if n == item {
+			return true
+		}
+		if atomic.CompareAndSwapUint32(&e.data[i], item, n) {
+			e.account(i, bits.OnesCount32(n)-bits.OnesCount32(item))
+			return true
+		}
+	}
+}
+
+func (e *dynEntry) walk(f func(x uint32) bool) {
+	sLen := int(e.getLen())
+	for i := 0; i < sLen; i++ {
+		// jump to each set bit by trailing zeros count.
+		for item := e.load(i); item != 0; item &= item - 1 {
+			if !f(uint32(i<<4 + bits.TrailingZeros32(item))) {
+				return
 			}
-			item >>= 1
 		}
 	}
 }
@@ -265,16 +579,15 @@ func dynGrowWork(s *Dynamic, old *dynEntry, cap uint32) bool {
 		cap:  newCap,
 		data: make([]uint32, newCap),
 	}
-	// evacute old node to new node
+	// evacute old node to new node, store keep the count.
 	for i := 0; i < int(old.getCap()); i++ {
 		// mask the height bit to freezeBit
 		item := old.freeze(i)
 		nn.store(i, item&^freezeBit)
 	}
-	nn.walk(func(x uint32) bool {
-		nn.count += 1
-		return true
-	})
+	if atomic.LoadUint32(&s.ranked) == 1 {
+		nn.rank = unsafe.Pointer(newRankIndex(nn))
+	}
 	ok := atomic.CompareAndSwapPointer(&s.node, unsafe.Pointer(old), unsafe.Pointer(nn))
 	if !ok {
 		panic("BUG: failed swapping head")
@@ -1,3 +1,3 @@
 module github.com/min1324/set
 
-go 1.16
+go 1.23
@@ -0,0 +1,101 @@
+package set
+
+import "sync/atomic"
+
+// Iterator is a cursor over the items of a Static or Dynamic set,
+// it yield the items in ascending order, and can be paused,
+// resumed and seek any time.
+//
+// Iterator keep the next item to look at, not a pointer to a word,
+// each Next reload the words of the set, so it stay correct while
+// Dynamic grows.
+//
+// Iterator does not correspond to any consistent snapshot of the set's
+// contents: the items returned are strictly increasing, an item
+// stored or deleted concurrently behind the cursor is not visit,
+// ahead of the cursor may or may not be visit.
+//
+// An Iterator must not be used by multiple goroutines simultaneously,
+// but many Iterator can walk the same set concurrently with Store and Delete.
+type Iterator struct {
+	// words of the set, reload on each Next.
+	bits func() bitSet
+
+	// next item to look at.
+	pos uint32
+
+	// no more item >= pos.
+	done bool
+}
+
+// Iterator return an Iterator point to the smallest item of the set.
+func (s *Static) Iterator() *Iterator {
+	return &Iterator{bits: func() bitSet { return s }}
+}
+
+// Iterator return an Iterator point to the smallest item of the set.
+func (s *Dynamic) Iterator() *Iterator {
+	return &Iterator{bits: func() bitSet { return s.getEntry() }}
+}
+
+// Next return the next item in the set, and move the cursor after it.
+// ok is false if no more item.
+// time complexity: O(N/32) for the gap to next item.
+func (it *Iterator) Next() (x uint32, ok bool) {
+	if it.done {
+		return 0, false
+	}
+	x, ok = nextBit(it.bits(), it.pos)
+	if !ok || x == ^uint32(0) {
+		// nothing after x.
+		it.done = true
+	}
+	if ok {
+		it.pos = x + 1
+	}
+	return x, ok
+}
+
+// Seek move the cursor to x, the next item return by Next is >= x.
+func (it *Iterator) Seek(x uint32) {
+	it.pos = x
+	it.done = false
+}
+
+// Reset move the cursor to the smallest item of the set.
+func (it *Iterator) Reset() { it.Seek(0) }
+
+// BaseIterator is a Iterator over the items of Base.
+type BaseIterator struct {
+	it  Iterator
+	min int32
+}
+
+// Iterator return an Iterator point to the smallest item of the set.
+func (s *Base) Iterator() *BaseIterator {
+	return &BaseIterator{
+		it:  *s.Static.Iterator(),
+		min: atomic.LoadInt32(&s.min),
+	}
+}
+
+// Next return the next item in the set, and move the cursor after it.
+// ok is false if no more item.
+func (it *BaseIterator) Next() (x int32, ok bool) {
+	v, ok := it.it.Next()
+	if !ok {
+		return 0, false
+	}
+	return it.min + int32(v), true
+}
+
+// Seek move the cursor to x, the next item return by Next is >= x.
+func (it *BaseIterator) Seek(x int32) {
+	if x < it.min {
+		x = it.min
+	}
+	it.it.Seek(uint32(x - it.min))
+}
+
+// Reset move the cursor to the smallest item of the set.
+func (it *BaseIterator) Reset() { it.it.Reset() }
@@ -0,0 +1,405 @@
+package set
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// MarshalJSON implements the json.Marshaler interface.
+// the set is encode as a sorted array, like [1,2,3].
+func (s *Static) MarshalJSON() ([]byte, error) { return marshalJSON(s, false) }
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// data can be a array [1,2,3] or range form [[1,5],[9,9]], or mix of them.
+// the set is init with the biggest item as max if not init yet,
+// and cleared before load.
+func (s *Static) UnmarshalJSON(data []byte) error {
+	runs, err := decodeJSONRuns(data)
+	if err != nil {
+		return err
+	}
+	if len(runs) > 0 {
+		s.OnceInit(int(runs.max()))
+	}
+	Clear(s)
+	return runs.addTo(s, 0, int64(s.getMax()))
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// the set is encode as a sorted array, like [1,2,3].
+func (s *Dynamic) MarshalJSON() ([]byte, error) { return marshalJSON(s, false) }
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// data can be a array [1,2,3] or range form [[1,5],[9,9]], or mix of them.
+// the set is init with the biggest item as max if not init yet,
+// and cleared before load.
+func (s *Dynamic) UnmarshalJSON(data []byte) error {
+	runs, err := decodeJSONRuns(data)
+	if err != nil {
+		return err
+	}
+	if len(runs) > 0 {
+		s.OnceInit(int(runs.max()))
+	}
+	Clear(s)
+	return runs.addTo(s, 0, int64(s.getMax()))
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// the set is encode as a sorted array, like [-1,2,3].
+func (s *Base) MarshalJSON() ([]byte, error) { return marshalJSON(s, false) }
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// data can be a array [1,2,3] or range form [[1,5],[9,9]], or mix of them.
+// the set is init with the smallest and biggest item as min and max
+// if not init yet, and cleared before load.
+func (s *Base) UnmarshalJSON(data []byte) error {
+	runs, err := decodeJSONRuns(data)
+	if err != nil {
+		return err
+	}
+	if len(runs) > 0 {
+		s.Init(int(min64(runs.max(), math.MaxInt32)), int(runs.min()))
+	}
+	Clear(&s.Static)
+	return runs.addTo(s, int64(atomic.LoadInt32(&s.min)), int64(atomic.LoadInt32(&s.max)))
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// the set is encode as an array, numbers first in ascending order,
+// then the other items order by their json.
+func (s *Map) MarshalJSON() ([]byte, error) {
+	type item struct {
+		raw   []byte
+		num   float64
+		isNum bool
+	}
+	var items []item
+	var err error
+	s.Range(func(x interface{}) bool {
+		var it item
+		if it.raw, err = json.Marshal(x); err != nil {
+			return false
+		}
+		if v, e := strconv.ParseFloat(string(it.raw), 64); e == nil {
+			it.num, it.isNum = v, true
+		}
+		items = append(items, it)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.isNum != b.isNum {
+			return a.isNum
+		}
+		if a.isNum {
+			return a.num < b.num
+		}
+		return bytes.Compare(a.raw, b.raw) < 0
+	})
+	buf := []byte{'['}
+	for i, it := range items {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, it.raw...)
+	}
+	return append(buf, ']'), nil
+}
+
+// mapRangeLimit is the most items a Map load from the range pairs of json,
+// each item of Map is a map entry, a bigger range is reject,
+// so that a few bytes of json can't hang the decoder.
+const mapRangeLimit = 1 << 20
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// data is an array of items, the integer numbers are load as int,
+// the other numbers as float64, and a pair [lo,hi] as int items in range.
+// the pairs hold at most mapRangeLimit items in all.
+// the set is cleared before load, and not changed if data is invalid.
+func (s *Map) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var items []interface{}
+	var pairs [][2]int64
+	var total uint64
+	for _, r := range raw {
+		if r = bytes.TrimSpace(r); len(r) > 0 && r[0] == '[' {
+			var pair [2]int64
+			if err := unmarshalPair(r, &pair); err != nil {
+				return err
+			}
+			if pair[0] < math.MinInt32 || pair[1] > math.MaxUint32 {
+				return fmt.Errorf("set: json range %v overflow", pair)
+			}
+			if total += uint64(pair[1]-pair[0]) + 1; total > mapRangeLimit {
+				return fmt.Errorf("set: json ranges over %d items", mapRangeLimit)
+			}
+			pairs = append(pairs, pair)
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(r))
+		dec.UseNumber()
+		var x interface{}
+		if err := dec.Decode(&x); err != nil {
+			return err
+		}
+		if n, ok := x.(json.Number); ok {
+			if i, err := strconv.Atoi(n.String()); err == nil {
+				x = i
+			} else if x, err = n.Float64(); err != nil {
+				return err
+			}
+		}
+		items = append(items, x)
+	}
+	s.Range(func(x interface{}) bool {
+		s.Remove(x)
+		return true
+	})
+	for _, pair := range pairs {
+		for x := pair[0]; x <= pair[1]; x++ {
+			s.Add(int(x))
+		}
+	}
+	for _, x := range items {
+		s.Add(x)
+	}
+	return nil
+}
+
+// Ranges wrap a Static, Dynamic, Base or Map, marshal it to json
+// in the compact range form, like [[1,5],[9,9]].
+// Map must only hold integer items.
+//
+//	json.Marshal(set.Ranges(s))
+//
+// the json can be unmarshal by the set itself.
+func Ranges(s interface{}) json.Marshaler { return rangesJSON{s} }
+
+type rangesJSON struct{ s interface{} }
+
+func (r rangesJSON) MarshalJSON() ([]byte, error) { return marshalJSON(r.s, true) }
+
+// marshalJSON encode the items of s as array,
+// or range form if ranges.
+func marshalJSON(s interface{}, ranges bool) ([]byte, error) {
+	walk, err := jsonWalk(s)
+	if err != nil {
+		return nil, err
+	}
+	buf := []byte{'['}
+	first := true
+	sep := func() {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+	}
+	if !ranges {
+		walk(func(x int64) bool {
+			sep()
+			buf = strconv.AppendInt(buf, x, 10)
+			return true
+		})
+		return append(buf, ']'), nil
+	}
+	var lo, hi int64
+	flush := func() {
+		sep()
+		buf = append(buf, '[')
+		buf = strconv.AppendInt(buf, lo, 10)
+		buf = append(buf, ',')
+		buf = strconv.AppendInt(buf, hi, 10)
+		buf = append(buf, ']')
+	}
+	empty := true
+	walk(func(x int64) bool {
+		switch {
+		case empty:
+			lo, hi, empty = x, x, false
+		case x == hi+1:
+			hi = x
+		default:
+			flush()
+			lo, hi = x, x
+		}
+		return true
+	})
+	if !empty {
+		flush()
+	}
+	return append(buf, ']'), nil
+}
+
+// jsonWalk return a function range the items of s in ascending order.
+func jsonWalk(s interface{}) (func(f func(x int64) bool), error) {
+	switch ss := s.(type) {
+	case *Base:
+		return func(f func(x int64) bool) {
+			ss.Range(func(x int32) bool { return f(int64(x)) })
+		}, nil
+	case *Map:
+		var xs []int64
+		var err error
+		ss.Range(func(x interface{}) bool {
+			v := reflect.ValueOf(x)
+			switch v.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				xs = append(xs, v.Int())
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				if v.Uint() > math.MaxInt64 {
+					err = fmt.Errorf("set: item %v overflow int64", x)
+					return false
+				}
+				xs = append(xs, int64(v.Uint()))
+			default:
+				err = fmt.Errorf("set: range form need integer item, got %T", x)
+				return false
+			}
+			return true
+		})
+		sort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })
+		return func(f func(x int64) bool) {
+			for i, x := range xs {
+				// skip the same number in different type.
+				if i > 0 && x == xs[i-1] {
+					continue
+				}
+				if !f(x) {
+					return
+				}
+			}
+		}, err
+	case Set:
+		return func(f func(x int64) bool) {
+			ss.Range(func(x uint32) bool { return f(int64(x)) })
+		}, nil
+	}
+	return nil, fmt.Errorf("set: can't marshal %T to json", s)
+}
+
+// jsonRuns is the decoded items of json, each run is [lo,hi].
+type jsonRuns [][2]int64
+
+func (r jsonRuns) min() int64 {
+	m := r[0][0]
+	for _, v := range r {
+		if v[0] < m {
+			m = v[0]
+		}
+	}
+	return m
+}
+
+func (r jsonRuns) max() int64 {
+	m := r[0][1]
+	for _, v := range r {
+		if v[1] > m {
+			m = v[1]
+		}
+	}
+	return m
+}
+
+// addTo add the runs to s, the items out of [lo,hi] are dropped,
+// and return ErrOverflow.
+func (r jsonRuns) addTo(s interface{}, lo, hi int64) error {
+	var err error
+	for _, v := range r {
+		a, b := v[0], v[1]
+		if a < lo || b > hi {
+			err = ErrOverflow
+			a, b = max64(a, lo), min64(b, hi)
+			if a > b {
+				continue
+			}
+		}
+		switch ss := s.(type) {
+		case *Base:
+			ss.AddRange(int32(a), int32(b))
+		case interface{ AddRange(lo, hi uint32) bool }:
+			ss.AddRange(uint32(a), uint32(b))
+		}
+	}
+	return err
+}
+
+// decodeJSONRuns decode a array of item or [lo,hi] pair.
+// the items must in range of int32 or uint32.
+func decodeJSONRuns(data []byte) (jsonRuns, error) {
+	var runs jsonRuns
+	// fast path for plain array.
+	var xs []int64
+	if err := json.Unmarshal(data, &xs); err == nil {
+		runs = make(jsonRuns, len(xs))
+		for i, x := range xs {
+			runs[i] = [2]int64{x, x}
+		}
+	} else {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		runs = make(jsonRuns, len(raw))
+		for i, r := range raw {
+			if r = bytes.TrimSpace(r); len(r) > 0 && r[0] == '[' {
+				var pair [2]int64
+				if err := unmarshalPair(r, &pair); err != nil {
+					return nil, err
+				}
+				runs[i] = pair
+				continue
+			}
+			var x int64
+			if err := json.Unmarshal(r, &x); err != nil {
+				return nil, err
+			}
+			runs[i] = [2]int64{x, x}
+		}
+	}
+	for _, v := range runs {
+		if v[0] < math.MinInt32 || v[1] > math.MaxUint32 {
+			return nil, fmt.Errorf("set: json range %v overflow", v)
+		}
+	}
+	return runs, nil
+}
+
+// unmarshalPair decode a [lo,hi] pair, lo must <= hi.
+func unmarshalPair(data []byte, pair *[2]int64) error {
+	var p []int64
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if len(p) != 2 || p[0] > p[1] {
+		return fmt.Errorf("set: invalid json range %s", data)
+	}
+	pair[0], pair[1] = p[0], p[1]
+	return nil
+}
+
+func max64(x, y int64) int64 {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+func min64(x, y int64) int64 {
+	if x < y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,108 @@
+package set_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+func TestJSON(t *testing.T) {
+	items := []uint32{1, 2, 3, 4, 5, 9, 100}
+	for _, c := range []struct {
+		s, d Interface
+	}{
+		{&set.Static{}, &set.Static{}},
+		{&set.Dynamic{}, &set.Dynamic{}},
+		{&set.Static{}, &set.Dynamic{}},
+	} {
+		t.Run(fmt.Sprintf("%T-%T", c.s, c.d), func(t *testing.T) {
+			c.s.OnceInit(1000)
+			set.Adds(c.s, items...)
+			data, err := json.Marshal(c.s)
+			if err != nil || string(data) != "[1,2,3,4,5,9,100]" {
+				t.Fatalf("Marshal() = %s %v", data, err)
+			}
+			data, err = json.Marshal(set.Ranges(c.s))
+			if err != nil || string(data) != "[[1,5],[9,9],[100,100]]" {
+				t.Fatalf("Marshal(Ranges) = %s %v", data, err)
+			}
+			// not init yet, max is the biggest item.
+			if err := json.Unmarshal(data, c.d); err != nil {
+				t.Fatal(err)
+			}
+			if !set.Equal(c.s, c.d) || c.d.Store(101) || !c.d.Store(100) {
+				t.Fatalf("Unmarshal() = %v", c.d)
+			}
+			// mix form, clear before load.
+			if err := json.Unmarshal([]byte(" [ 7, [1 ,3] ] "), c.d); err != nil || c.d.(fmt.Stringer).String() != "{1 2 3 7}" {
+				t.Fatalf("Unmarshal() mix = %v %v", c.d, err)
+			}
+			if err := json.Unmarshal([]byte("[1,200]"), c.d); !errors.Is(err, set.ErrOverflow) || c.d.(fmt.Stringer).String() != "{1}" {
+				t.Fatalf("Unmarshal() overflow = %v %v", c.d, err)
+			}
+			for _, bad := range []string{`{}`, `[-1]`, `[[3,1]]`, `[[1,2,3]]`, `[1.5]`, `["a"]`, `[4294967296]`} {
+				if err := json.Unmarshal([]byte(bad), c.d); err == nil {
+					t.Fatalf("Unmarshal(%s) no err", bad)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONBase(t *testing.T) {
+	b := set.NewBase(100, -100)
+	for _, x := range []int32{-100, -99, -3, 40, 100} {
+		b.Add(x)
+	}
+	data, err := json.Marshal(b)
+	if err != nil || string(data) != "[-100,-99,-3,40,100]" {
+		t.Fatalf("Marshal() = %s %v", data, err)
+	}
+	data, _ = json.Marshal(set.Ranges(b))
+	if string(data) != "[[-100,-99],[-3,-3],[40,40],[100,100]]" {
+		t.Fatalf("Marshal(Ranges) = %s", data)
+	}
+	var c set.Base
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.String() != b.String() || c.Add(-101) {
+		t.Fatalf("Unmarshal() = %v", &c)
+	}
+}
+
+func TestJSONMap(t *testing.T) {
+	var m set.Map
+	for _, x := range []interface{}{3, 1, "b", 2, "a"} {
+		m.Add(x)
+	}
+	data, err := json.Marshal(&m)
+	if err != nil || string(data) != `[1,2,3,"a","b"]` {
+		t.Fatalf("Marshal() = %s %v", data, err)
+	}
+	if _, err := json.Marshal(set.Ranges(&m)); err == nil {
+		t.Fatalf("Marshal(Ranges) with string item no err")
+	}
+	var c set.Map
+	if err := json.Unmarshal([]byte(`[[1,3],"a",1.5]`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Has(1) || !c.Has(2) || !c.Has(3) || !c.Has("a") || !c.Has(1.5) || c.Has(4) {
+		t.Fatalf("Unmarshal() = %v", &c)
+	}
+	// a huge range is reject, the set not change.
+	for _, in := range []string{`[[0,9223372036854775807]]`, `[[0,600000],[0,600000]]`, `[[-4294967296,0]]`} {
+		if err := json.Unmarshal([]byte(in), &c); err == nil || !c.Has("a") || c.Has(0) {
+			t.Fatalf("Unmarshal(%s) = %v", in, err)
+		}
+	}
+	m.Remove("a")
+	m.Remove("b")
+	data, _ = json.Marshal(set.Ranges(&m))
+	if string(data) != "[[1,3]]" {
+		t.Fatalf("Marshal(Ranges) = %s", data)
+	}
+}
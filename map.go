@@ -46,7 +46,8 @@ func (s *Map) Range(f func(x interface{}) bool) {
 	})
 }
 
-// String returns the set as a string of the form "{1 2 3}".
+// String returns the set as a string of the form "{1 2 3}",
+// the string items are quoted, like {1 "a"}.
 func (s *Map) String() string {
 	var buf bytes.Buffer
 	buf.WriteByte('{')
@@ -54,7 +55,12 @@ func (s *Map) String() string {
 		if buf.Len() > len("{") {
 			buf.WriteByte(' ')
 		}
-		fmt.Fprintf(&buf, "%d", x)
+		switch v := x.(type) {
+		case string:
+			fmt.Fprintf(&buf, "%q", v)
+		default:
+			fmt.Fprint(&buf, v)
+		}
 		return true
 	})
 	buf.WriteByte('}')
@@ -0,0 +1,96 @@
+package set
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Jaccard return the jaccard similarity of s and t, |S∩T| / |S∪T|.
+// return 1 if both s and t are empty.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func Jaccard(s, t Set) float64 {
+	sn, tn, inter := countBoth(s, t)
+	return ratio(float64(inter), float64(sn+tn-inter), sn+tn)
+}
+
+// Dice return the dice coefficient of s and t, 2|S∩T| / (|S|+|T|).
+// return 1 if both s and t are empty.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func Dice(s, t Set) float64 {
+	sn, tn, inter := countBoth(s, t)
+	return ratio(float64(2*inter), float64(sn+tn), sn+tn)
+}
+
+// Overlap return the overlap coefficient of s and t, |S∩T| / min(|S|,|T|).
+// return 1 if both s and t are empty, 0 if only one is empty.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func Overlap(s, t Set) float64 {
+	sn, tn, inter := countBoth(s, t)
+	return ratio(float64(inter), float64(min(sn, tn)), sn+tn)
+}
+
+// Cosine return the cosine similarity of s and t, |S∩T| / sqrt(|S|*|T|).
+// return 1 if both s and t are empty, 0 if only one is empty.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func Cosine(s, t Set) float64 {
+	sn, tn, inter := countBoth(s, t)
+	return ratio(float64(inter), math.Sqrt(float64(sn)*float64(tn)), sn+tn)
+}
+
+// Hamming return the hamming distance of s and t,
+// the number of items in s or in t but not both.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func Hamming(s, t Set) int {
+	sn, tn, inter := countBoth(s, t)
+	return sn + tn - 2*inter
+}
+
+// ratio return x/y, if y is 0,
+// return 1 if total is 0 (both empty), or return 0.
+func ratio(x, y float64, total int) float64 {
+	if y == 0 {
+		if total == 0 {
+			return 1
+		}
+		return 0
+	}
+	return x / y
+}
+
+// countBoth return |S|, |T| and |S∩T| in one pass.
+// Static and Dynamic are counted with popcount of each word.
+func countBoth(s, t Set) (sn, tn, inter int) {
+	ss, sok := toBitSet(s)
+	tt, tok := toBitSet(t)
+	if !sok || !tok {
+		// probe t by Load, Size(t) is O(1) or O(containers) for most sets,
+		// only a generic t is walked once more.
+		s.Range(func(x uint32) bool {
+			sn += 1
+			if t.Load(x) {
+				inter += 1
+			}
+			return true
+		})
+		return sn, Size(t), inter
+	}
+	w := opWidth(ss, tt)
+	n := max(wordCount(ss, w), wordCount(tt, w))
+	for i := 0; i < n; i++ {
+		sw, tw := loadWord(ss, i, w), loadWord(tt, i, w)
+		sn += bits.OnesCount32(sw)
+		tn += bits.OnesCount32(tw)
+		inter += bits.OnesCount32(sw & tw)
+	}
+	return sn, tn, inter
+}
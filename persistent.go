@@ -0,0 +1,589 @@
+package set
+
+import (
+	"math/bits"
+)
+
+// Persistent an immutable set of non-negative integers.
+// Its zero value represents the empty set.
+//
+// With and Without return a new version of the set,
+// which share all nodes but the path to the changed item with the old one.
+// the set algebra merge two sets node by node,
+// and share the nodes not changed.
+//
+// Persistent never change once create, readers need no atomics nor lock,
+// only the pointer of the current version must be publish safely,
+// like atomic.Pointer[Persistent].
+//
+// it implements Set, but Store and Delete always fail as overflow,
+// so Clear and the *Into ops leave a Persistent unchanged.
+type Persistent struct {
+	root *pnode
+}
+
+// the items are hold in a bitmapped trie of 4 levels,
+// each level index 6 bits of item by the bitmap of present children,
+// the leaf hold 512 items in 8 words.
+const (
+	pnodeBits  = 6
+	pnodeMask  = 1<<pnodeBits - 1
+	pleafShift = 9
+	pleafWords = 1 << pleafShift / 64
+	prootShift = pleafShift + 3*pnodeBits
+)
+
+// pnode is a node of Persistent trie, never change once share.
+// a nil node is empty, so that no node is empty.
+type pnode struct {
+	// number of items in node.
+	count int
+
+	// internal node, kids[k] is the child of k-th set bit of bitmap.
+	bitmap uint64
+	kids   []*pnode
+
+	// leaf node, bit j of words[i] is item base+64*i+j.
+	words []uint64
+}
+
+// child return the child k of internal node n, nil if not present.
+func (n *pnode) child(k uint32) *pnode {
+	if n == nil || n.bitmap&(1<<k) == 0 {
+		return nil
+	}
+	return n.kids[bits.OnesCount64(n.bitmap&(1<<k-1))]
+}
+
+// setChild return a copy of n with child k replaced by c,
+// c is nil to remove the child, return nil if n become empty.
+func (n *pnode) setChild(k uint32, c *pnode) *pnode {
+	old := n.child(k)
+	m := &pnode{}
+	if n != nil {
+		m.count, m.bitmap = n.count, n.bitmap
+	}
+	i := bits.OnesCount64(m.bitmap & (1<<k - 1))
+	m.kids = make([]*pnode, 0, bits.OnesCount64(m.bitmap)+1)
+	if n != nil {
+		m.kids = append(m.kids, n.kids[:i]...)
+	}
+	if c != nil {
+		m.kids = append(m.kids, c)
+		m.count += c.count
+		m.bitmap |= 1 << k
+	} else {
+		m.bitmap &^= 1 << k
+	}
+	if old != nil {
+		m.count -= old.count
+		i += 1
+	}
+	if n != nil {
+		m.kids = append(m.kids, n.kids[i:]...)
+	}
+	if m.count == 0 {
+		return nil
+	}
+	return m
+}
+
+// NewPersistent return a Persistent set with items xs.
+// time complexity: O(len(xs))
+func NewPersistent(xs ...uint32) *Persistent {
+	var b pbuilder
+	for _, x := range xs {
+		b.orWord(x&^63, 1<<(x&63))
+	}
+	return &Persistent{root: b.root}
+}
+
+// ToPersistent convert Set to Persistent set
+// if set if Persistent,return ptr.
+// or return a copy with set.
+// time complexity: O(N/64) for Static and Dynamic, O(N) for the others.
+func ToPersistent(s Set) *Persistent {
+	if p, ok := s.(*Persistent); ok {
+		return p
+	}
+	var b pbuilder
+	if ss, ok := toBitSet(s); ok {
+		walkWords(ss, func(base uint32, word uint64) bool {
+			b.orWord(base, word)
+			return true
+		})
+	} else {
+		s.Range(func(x uint32) bool {
+			b.orWord(x&^63, 1<<(x&63))
+			return true
+		})
+	}
+	return &Persistent{root: b.root}
+}
+
+// pbuilder build a trie in place,
+// all nodes are own by the builder until it done.
+type pbuilder struct {
+	root *pnode
+}
+
+// orWord set the bits of word at base, base is a multiple of 64.
+func (b *pbuilder) orWord(base uint32, word uint64) {
+	if word == 0 {
+		return
+	}
+	if b.root == nil {
+		b.root = &pnode{}
+	}
+	path := [prootShift/pnodeBits + 1]*pnode{}
+	n := b.root
+	depth := 0
+	for shift := uint32(prootShift); shift >= pleafShift; shift -= pnodeBits {
+		path[depth] = n
+		depth += 1
+		k := (base >> shift) & pnodeMask
+		c := n.child(k)
+		if c == nil {
+			c = &pnode{}
+			if shift == pleafShift {
+				c.words = make([]uint64, pleafWords)
+			}
+			i := bits.OnesCount64(n.bitmap & (1<<k - 1))
+			n.kids = append(n.kids, nil)
+			copy(n.kids[i+1:], n.kids[i:])
+			n.kids[i] = c
+			n.bitmap |= 1 << k
+		}
+		n = c
+	}
+	i := (base >> 6) & (pleafWords - 1)
+	delta := bits.OnesCount64(word &^ n.words[i])
+	n.words[i] |= word
+	n.count += delta
+	for _, p := range path[:depth] {
+		p.count += delta
+	}
+}
+
+// OnceInit do nothing, Persistent need no init.
+func (s *Persistent) OnceInit(max int) {}
+
+// Load reports whether the set contains the non-negative value x.
+// time complexity: O(1)
+func (s *Persistent) Load(x uint32) bool {
+	n := s.root
+	for shift := uint32(prootShift); n != nil && shift >= pleafShift; shift -= pnodeBits {
+		n = n.child((x >> shift) & pnodeMask)
+	}
+	if n == nil {
+		return false
+	}
+	return (n.words[(x>>6)&(pleafWords-1)]>>(x&63))&1 == 1
+}
+
+// Store always return false, Persistent is immutable, use With.
+func (s *Persistent) Store(x uint32) bool { return false }
+
+// Delete always return false, Persistent is immutable, use Without.
+func (s *Persistent) Delete(x uint32) bool { return false }
+
+// LoadOrStore report x if in set, ok always false, Persistent is immutable.
+func (s *Persistent) LoadOrStore(x uint32) (loaded, ok bool) { return s.Load(x), false }
+
+// LoadAndDelete report x if in set, ok always false, Persistent is immutable.
+func (s *Persistent) LoadAndDelete(x uint32) (loaded, ok bool) { return s.Load(x), false }
+
+// With return a new set of s and x,
+// the new set share all nodes with s but the path to x.
+// return s itself if x is in s.
+// time complexity: O(1)
+func (s *Persistent) With(x uint32) *Persistent {
+	root := pwith(s.root, x, prootShift, true)
+	if root == s.root {
+		return s
+	}
+	return &Persistent{root: root}
+}
+
+// Without return a new set of s without x,
+// the new set share all nodes with s but the path to x.
+// return s itself if x is not in s.
+// time complexity: O(1)
+func (s *Persistent) Without(x uint32) *Persistent {
+	root := pwith(s.root, x, prootShift, false)
+	if root == s.root {
+		return s
+	}
+	return &Persistent{root: root}
+}
+
+// pwith return a copy of n with x set if add, or clear,
+// return n itself if not changed.
+func pwith(n *pnode, x uint32, shift uint32, add bool) *pnode {
+	if shift < pleafShift {
+		i, bit := (x>>6)&(pleafWords-1), uint64(1)<<(x&63)
+		if (n != nil && n.words[i]&bit != 0) == add {
+			return n
+		}
+		m := &pnode{words: make([]uint64, pleafWords)}
+		if n != nil {
+			copy(m.words, n.words)
+			m.count = n.count
+		}
+		if add {
+			m.words[i] |= bit
+			m.count += 1
+		} else {
+			m.words[i] &^= bit
+			m.count -= 1
+		}
+		if m.count == 0 {
+			return nil
+		}
+		return m
+	}
+	k := (x >> shift) & pnodeMask
+	c := n.child(k)
+	nc := pwith(c, x, shift-pnodeBits, add)
+	if nc == c {
+		return n
+	}
+	return n.setChild(k, nc)
+}
+
+// Len return the number of items in the set.
+// time complexity: O(1)
+func (s *Persistent) Len() int {
+	if s.root == nil {
+		return 0
+	}
+	return s.root.count
+}
+
+// Range calls f sequentially for each item in the set, in ascending order.
+// If f returns false, range stops the iteration.
+// time complexity: O(N)
+func (s *Persistent) Range(f func(x uint32) bool) {
+	pwalk(s.root, prootShift, 0, f)
+}
+
+// pwalk calls f for each item of node n at base, return false if f stops.
+func pwalk(n *pnode, shift, base uint32, f func(x uint32) bool) bool {
+	if n == nil {
+		return true
+	}
+	if shift < pleafShift {
+		for i, w := range n.words {
+			for ; w != 0; w &= w - 1 {
+				if !f(base + uint32(i*64+bits.TrailingZeros64(w))) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	i := 0
+	for bm := n.bitmap; bm != 0; bm &= bm - 1 {
+		k := uint32(bits.TrailingZeros64(bm))
+		if !pwalk(n.kids[i], shift-pnodeBits, base|k<<shift, f) {
+			return false
+		}
+		i += 1
+	}
+	return true
+}
+
+// Min return the smallest item in the set.
+// ok is false if the set is empty.
+// time complexity: O(1)
+func (s *Persistent) Min() (x uint32, ok bool) {
+	n := s.root
+	if n == nil {
+		return 0, false
+	}
+	for shift := uint32(prootShift); shift >= pleafShift; shift -= pnodeBits {
+		x |= uint32(bits.TrailingZeros64(n.bitmap)) << shift
+		n = n.kids[0]
+	}
+	for i, w := range n.words {
+		if w != 0 {
+			return x + uint32(i*64+bits.TrailingZeros64(w)), true
+		}
+	}
+	return 0, false
+}
+
+// Max return the biggest item in the set.
+// ok is false if the set is empty.
+// time complexity: O(1)
+func (s *Persistent) Max() (x uint32, ok bool) {
+	n := s.root
+	if n == nil {
+		return 0, false
+	}
+	for shift := uint32(prootShift); shift >= pleafShift; shift -= pnodeBits {
+		x |= uint32(63-bits.LeadingZeros64(n.bitmap)) << shift
+		n = n.kids[len(n.kids)-1]
+	}
+	for i := len(n.words) - 1; i >= 0; i-- {
+		if w := n.words[i]; w != 0 {
+			return x + uint32(i*64+63-bits.LeadingZeros64(w)), true
+		}
+	}
+	return 0, false
+}
+
+// Union return the union set of s and t, item in s or in t.
+// the nodes only in one set are shared.
+// time complexity: O(N/64), O(1) for the shared nodes.
+func (s *Persistent) Union(t *Persistent) *Persistent {
+	return &Persistent{root: pmerge(s.root, t.root, prootShift, opUnion)}
+}
+
+// Intersect return the intersection set of s and t, item in s and in t.
+// time complexity: O(N/64), O(1) for the shared nodes.
+func (s *Persistent) Intersect(t *Persistent) *Persistent {
+	return &Persistent{root: pmerge(s.root, t.root, prootShift, opIntersect)}
+}
+
+// Difference return the difference set of s and t, item in s and not in t.
+// time complexity: O(N/64), O(1) for the shared nodes.
+func (s *Persistent) Difference(t *Persistent) *Persistent {
+	return &Persistent{root: pmerge(s.root, t.root, prootShift, opDifference)}
+}
+
+// SymmetricDifference return the items in s or in t but not both.
+// time complexity: O(N/64), O(1) for the shared nodes.
+func (s *Persistent) SymmetricDifference(t *Persistent) *Persistent {
+	return &Persistent{root: pmerge(s.root, t.root, prootShift, opComplement)}
+}
+
+// persistentOperation return the flag operation of s and t.
+func persistentOperation(s, t *Persistent, flag opFlag) *Persistent {
+	return &Persistent{root: pmerge(s.root, t.root, prootShift, flag)}
+}
+
+// pmerge return the flag operation of node a and b,
+// share a or b if the result is the same.
+func pmerge(a, b *pnode, shift uint32, flag opFlag) *pnode {
+	switch flag {
+	case opUnion:
+		if a == nil || a == b {
+			return b
+		}
+		if b == nil {
+			return a
+		}
+	case opIntersect:
+		if a == nil || b == nil {
+			return nil
+		}
+		if a == b {
+			return a
+		}
+	case opDifference:
+		if a == nil || a == b {
+			return nil
+		}
+		if b == nil {
+			return a
+		}
+	case opComplement:
+		if a == b {
+			return nil
+		}
+		if a == nil {
+			return b
+		}
+		if b == nil {
+			return a
+		}
+	}
+	m := &pnode{}
+	if shift < pleafShift {
+		m.words = make([]uint64, pleafWords)
+		for i := range m.words {
+			w := opWord(flag, uint32(a.words[i]), uint32(b.words[i]))
+			hi := opWord(flag, uint32(a.words[i]>>32), uint32(b.words[i]>>32))
+			m.words[i] = uint64(w) | uint64(hi)<<32
+			m.count += bits.OnesCount64(m.words[i])
+		}
+	} else {
+		bm := a.bitmap | b.bitmap
+		if flag == opIntersect {
+			bm = a.bitmap & b.bitmap
+		}
+		for ; bm != 0; bm &= bm - 1 {
+			k := uint32(bits.TrailingZeros64(bm))
+			if c := pmerge(a.child(k), b.child(k), shift-pnodeBits, flag); c != nil {
+				m.kids = append(m.kids, c)
+				m.bitmap |= 1 << k
+				m.count += c.count
+			}
+		}
+	}
+	switch {
+	case m.count == 0:
+		return nil
+	case psame(m, a):
+		return a
+	case psame(m, b):
+		return b
+	}
+	return m
+}
+
+// psame report whether node m has the same items of n,
+// the children of m are compare by pointer.
+func psame(m, n *pnode) bool {
+	if m.count != n.count || m.bitmap != n.bitmap {
+		return false
+	}
+	for i := range m.kids {
+		if m.kids[i] != n.kids[i] {
+			return false
+		}
+	}
+	for i := range m.words {
+		if m.words[i] != n.words[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal report whether s and t has the same items.
+// time complexity: O(N/64), O(1) for the shared nodes.
+func (s *Persistent) Equal(t *Persistent) bool { return pequal(s.root, t.root, prootShift) }
+
+func pequal(a, b *pnode, shift uint32) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil || a.count != b.count || a.bitmap != b.bitmap {
+		return false
+	}
+	if shift < pleafShift {
+		for i := range a.words {
+			if a.words[i] != b.words[i] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := range a.kids {
+		if !pequal(a.kids[i], b.kids[i], shift-pnodeBits) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubset report whether every item of s is in t, s ⊆ t.
+// time complexity: O(N/64), O(1) for the shared nodes.
+func (s *Persistent) IsSubset(t *Persistent) bool { return psubset(s.root, t.root, prootShift) }
+
+func psubset(a, b *pnode, shift uint32) bool {
+	if a == nil || a == b {
+		return true
+	}
+	if b == nil || a.count > b.count || a.bitmap&^b.bitmap != 0 {
+		return false
+	}
+	if shift < pleafShift {
+		for i := range a.words {
+			if a.words[i]&^b.words[i] != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	for bm := a.bitmap; bm != 0; bm &= bm - 1 {
+		k := uint32(bits.TrailingZeros64(bm))
+		if !psubset(a.child(k), b.child(k), shift-pnodeBits) {
+			return false
+		}
+	}
+	return true
+}
+
+// Disjoint report whether s and t has no item in common.
+// time complexity: O(N/64)
+func (s *Persistent) Disjoint(t *Persistent) bool { return pdisjoint(s.root, t.root, prootShift) }
+
+func pdisjoint(a, b *pnode, shift uint32) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	if a == b {
+		return false
+	}
+	if shift < pleafShift {
+		for i := range a.words {
+			if a.words[i]&b.words[i] != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	for bm := a.bitmap & b.bitmap; bm != 0; bm &= bm - 1 {
+		k := uint32(bits.TrailingZeros64(bm))
+		if !pdisjoint(a.child(k), b.child(k), shift-pnodeBits) {
+			return false
+		}
+	}
+	return true
+}
+
+// ToStatic return a Static set of the items, with the biggest item as max.
+// the items bigger than maximum are dropped.
+// time complexity: O(N/64)
+func (s *Persistent) ToStatic() *Static {
+	var p Static
+	x, _ := s.Max()
+	if x > maximum {
+		x = maximum
+	}
+	p.OnceInit(int(x))
+	s.toWords(&p)
+	return &p
+}
+
+// ToDynamic return a Dynamic set of the items, which grow as need.
+// the items bigger than maximum are dropped.
+// time complexity: O(N/64)
+func (s *Persistent) ToDynamic() *Dynamic {
+	var p Dynamic
+	p.OnceInit(0)
+	s.toWords(&p)
+	return &p
+}
+
+// toWords set the words of the items to p.
+func (s *Persistent) toWords(p wordSet) {
+	pwalkLeaf(s.root, prootShift, 0, func(base uint32, words []uint64) {
+		for i, w := range words {
+			orWord64(p, int(base/64)+i, w)
+		}
+	})
+}
+
+// pwalkLeaf calls f for each leaf of node n with the first item of leaf.
+func pwalkLeaf(n *pnode, shift, base uint32, f func(base uint32, words []uint64)) {
+	if n == nil {
+		return
+	}
+	if shift < pleafShift {
+		f(base, n.words)
+		return
+	}
+	i := 0
+	for bm := n.bitmap; bm != 0; bm &= bm - 1 {
+		k := uint32(bits.TrailingZeros64(bm))
+		pwalkLeaf(n.kids[i], shift-pnodeBits, base|k<<shift, f)
+		i += 1
+	}
+}
+
+// String returns the set as a string of the form "{1 2 3}".
+func (s *Persistent) String() string { return String(s) }
@@ -0,0 +1,232 @@
+package set_test
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+func TestPersistent(t *testing.T) {
+	var empty set.Persistent
+	if empty.Len() != 0 || empty.Load(0) || empty.String() != "{}" {
+		t.Fatalf("Persistent{} not empty")
+	}
+	if _, ok := empty.Min(); ok {
+		t.Fatalf("Persistent{} Min ok")
+	}
+	p1 := empty.With(5)
+	p2 := p1.With(1 << 20).With(64).With(5)
+	p3 := p2.Without(5)
+	if empty.Len() != 0 || p1.String() != "{5}" {
+		t.Fatalf("With() changed old version: %v %v", &empty, p1)
+	}
+	if p2.String() != "{5 64 1048576}" || p3.String() != "{64 1048576}" {
+		t.Fatalf("With() = %v, Without() = %v", p2, p3)
+	}
+	if p1.With(5) != p1 || p3.Without(5) != p3 {
+		t.Fatalf("With() exist item not return itself")
+	}
+	if x, ok := p2.Min(); !ok || x != 5 {
+		t.Fatalf("Min() = %d %v", x, ok)
+	}
+	if x, ok := p2.Max(); !ok || x != 1<<20 {
+		t.Fatalf("Max() = %d %v", x, ok)
+	}
+	if p2.Store(7) || p2.Delete(5) || !p2.Load(5) {
+		t.Fatalf("Persistent not immutable")
+	}
+	if p := p3.Without(64).Without(1 << 20); p.Len() != 0 || p.String() != "{}" {
+		t.Fatalf("Without() all = %v", p)
+	}
+	if p := set.NewPersistent(^uint32(0), 0); p.Len() != 2 || !p.Load(^uint32(0)) {
+		t.Fatalf("NewPersistent() = %v", p)
+	}
+}
+
+func TestPersistentAlgebra(t *testing.T) {
+	const max = 1 << 16
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		a, b := set.NewStatic(max), set.NewStatic(max)
+		for i := 0; i < r.Intn(3000); i++ {
+			a.Store(uint32(r.Intn(max)))
+		}
+		for i := 0; i < r.Intn(3000); i++ {
+			b.Store(uint32(r.Intn(max)))
+		}
+		pa, pb := set.ToPersistent(a), set.ToPersistent(b)
+		if !set.Equal(pa, a) || pa.Len() != set.Size(a) {
+			t.Fatalf("ToPersistent() = %d items want %d", pa.Len(), set.Size(a))
+		}
+		for _, c := range []struct {
+			name string
+			got  *set.Persistent
+			want set.Set
+		}{
+			{"Union", pa.Union(pb), set.Union(a, b)},
+			{"Intersect", pa.Intersect(pb), set.Intersect(a, b)},
+			{"Difference", pa.Difference(pb), set.Difference(a, b)},
+			{"SymmetricDifference", pa.SymmetricDifference(pb), set.Complement(a, b)},
+		} {
+			if !set.Equal(c.got.ToStatic(), c.want) || c.got.Len() != set.Size(c.want) {
+				t.Fatalf("%s() = %d items want %d", c.name, c.got.Len(), set.Size(c.want))
+			}
+		}
+		if pa.IsSubset(pb) != set.IsSubset(a, b) || pa.Disjoint(pb) != set.Disjoint(a, b) {
+			t.Fatalf("IsSubset() or Disjoint() err")
+		}
+		if u := pa.Union(pb); !pa.IsSubset(u) || !u.Equal(pb.Union(pa)) {
+			t.Fatalf("Union() not superset")
+		}
+	}
+}
+
+func TestPersistentShare(t *testing.T) {
+	a := set.NewPersistent(1, 1000, 100000)
+	if !a.Union(a).Equal(a) || a.Intersect(a).Len() != 3 {
+		t.Fatalf("Union() self err")
+	}
+	b := a.With(100001)
+	// only the path to 100001 is new, the others are shared.
+	if d := b.Difference(a); d.String() != "{100001}" {
+		t.Fatalf("Difference() = %v", d)
+	}
+	if i := a.Intersect(b); !i.Equal(a) || !a.IsSubset(b) || b.IsSubset(a) {
+		t.Fatalf("Intersect() = %v", i)
+	}
+	if s := a.SymmetricDifference(a); s.Len() != 0 {
+		t.Fatalf("SymmetricDifference() self = %v", s)
+	}
+}
+
+func TestPersistentConvert(t *testing.T) {
+	p := set.NewPersistent(0, 63, 64, 511, 512, 70000)
+	s, d := p.ToStatic(), p.ToDynamic()
+	if s.String() != p.String() || d.String() != p.String() {
+		t.Fatalf("ToStatic() = %v, ToDynamic() = %v", s, d)
+	}
+	if !s.Store(1) || p.Load(1) || !d.Store(1<<20) {
+		t.Fatalf("ToStatic() not mutable")
+	}
+	if set.ToStatic(p).String() != p.String() || set.ToDynamic(p).String() != p.String() {
+		t.Fatalf("ToStatic() public err")
+	}
+	if !set.Equal(set.ToPersistent(d), d) || set.ToPersistent(p) != p {
+		t.Fatalf("ToPersistent() err")
+	}
+	var r set.Roaring
+	r.Store(7)
+	r.Store(70000)
+	if q := set.ToPersistent(&r); q.String() != "{7 70000}" {
+		t.Fatalf("ToPersistent() = %v", q)
+	}
+}
+
+func TestPersistentPublic(t *testing.T) {
+	a, b := set.NewPersistent(1, 2, 3), set.NewPersistent(3, 4)
+	if u, ok := set.Union(a, b).(*set.Persistent); !ok || u.String() != "{1 2 3 4}" {
+		t.Fatalf("Union() = %v", u)
+	}
+	if d := set.Difference(a, b); set.String(d) != "{1 2}" {
+		t.Fatalf("Difference() = %v", d)
+	}
+	if set.Size(a) != 3 || set.Copy(a) != set.Set(a) || !set.Equal(a, set.NewStatic(10, 1, 2, 3)) {
+		t.Fatalf("Size(), Copy() or Equal() err")
+	}
+	if !set.IsSubset(set.NewPersistent(1, 3), a) || !set.IsProperSubset(set.NewPersistent(1, 3), a) || set.IsProperSubset(a, a) {
+		t.Fatalf("IsSubset() err")
+	}
+	if set.Disjoint(a, b) || !set.Disjoint(a, set.NewPersistent(9)) {
+		t.Fatalf("Disjoint() err")
+	}
+	// mix with other type return Persistent, the items above maximum kept.
+	if i, ok := set.Intersect(a, set.NewStatic(10, 2, 3, 9)).(*set.Persistent); !ok || i.String() != "{2 3}" {
+		t.Fatalf("Intersect() = %v", i)
+	}
+	const big = 4000000000
+	r := getRoaring(10, 5, 6)
+	r.Store(1)
+	for _, c := range []struct {
+		name string
+		got  set.Set
+		want []uint32
+	}{
+		{"Union(Persistent, Static)", set.Union(set.NewPersistent(big), set.NewStatic(10, 1)), []uint32{1, big}},
+		{"Union(Dynamic, Persistent)", set.Union(set.NewDynamic(0, 1), set.NewPersistent(big)), []uint32{1, big}},
+		{"Difference(Persistent, Static)", set.Difference(set.NewPersistent(1, 70000, big), set.NewStatic(100000, 1, 70000)), []uint32{big}},
+		{"Complement(Roaring, Persistent)", set.Complement(r, set.NewPersistent(5, big)), []uint32{1, big}},
+		{"Intersect(Persistent, Other)", set.Intersect(set.NewPersistent(2, big), getMutexSet(10, 0, 5)), []uint32{2}},
+	} {
+		if _, ok := c.got.(*set.Persistent); !ok || !reflect.DeepEqual(set.Items(c.got), c.want) {
+			t.Fatalf("%s = %T %v, want %v", c.name, c.got, set.Items(c.got), c.want)
+		}
+	}
+	var n int
+	for x := range a.All() {
+		n += int(x)
+	}
+	if n != 6 {
+		t.Fatalf("All() sum = %d", n)
+	}
+}
+
+// TestPersistentConcurrent readers share the versions without lock,
+// and build new versions from them concurrently.
+func TestPersistentConcurrent(t *testing.T) {
+	versions := make([]*set.Persistent, 1, 1001)
+	versions[0] = &set.Persistent{}
+	for i := uint32(1); i <= 1000; i++ {
+		versions = append(versions, versions[i-1].With(i*37))
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < len(versions); i += 4 {
+				p := versions[i]
+				if p.Len() != i || (i > 0 && !p.Load(uint32(i)*37)) || p.Load(uint32(i+1)*37) {
+					t.Errorf("version %d = %d items", i, p.Len())
+					return
+				}
+				p.Without(uint32(i) * 37).Union(versions[len(versions)-1-i])
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestPersistentIntersectEmpty(t *testing.T) {
+	a, empty := set.NewPersistent(1, 2, 3), set.NewPersistent()
+	far := set.NewPersistent(1<<20, 1<<30)
+	for _, c := range []struct {
+		name string
+		got  set.Set
+	}{
+		{"a.Intersect(empty)", a.Intersect(empty)},
+		{"empty.Intersect(a)", empty.Intersect(a)},
+		{"Intersect(a, empty)", set.Intersect(a, empty)},
+		{"Intersect(empty, a)", set.Intersect(empty, a)},
+		{"a.Intersect(far)", a.Intersect(far)},
+		{"far.Intersect(a)", far.Intersect(a)},
+	} {
+		if set.Size(c.got) != 0 || set.String(c.got) != "{}" {
+			t.Fatalf("%s = %v", c.name, set.String(c.got))
+		}
+	}
+	if !a.Intersect(a.With(9)).Equal(a) {
+		t.Fatalf("Intersect() superset = %v", a.Intersect(a.With(9)))
+	}
+}
+
+func TestPersistentInto(t *testing.T) {
+	p := set.NewPersistent(1, 2, 3)
+	set.Clear(p)
+	set.UnionInto(p, set.NewStatic(10, 7), set.NewStatic(10, 8))
+	if p.String() != "{1 2 3}" {
+		t.Fatalf("Persistent changed to %v", p)
+	}
+}
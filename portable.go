@@ -0,0 +1,296 @@
+package set
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// roaring portable format, the same as Java and C roaring bitmap:
+//
+//	cookie  uint32 roaringCookieNoRun, then uint32 number of containers n,
+//	        or roaringCookie|(n-1)<<16, then (n+7)/8 bytes bitset of run containers.
+//	header  n pairs of uint16 key and uint16 cardinality-1.
+//	offsets n uint32 offset of each container from the cookie,
+//	        omit if roaringCookie and n < roaringNoOffset.
+//	containers:
+//	        run container: uint16 number of runs, pairs of uint16 start and length-1.
+//	        bitmap container: cardinality > 4096, 1024 uint64.
+//	        array container: sorted uint16.
+//
+// all integers are little endian.
+const (
+	roaringCookieNoRun = 12346
+	roaringCookie      = 12347
+	roaringNoOffset    = 4
+)
+
+// WriteRoaring write s to w in the roaring portable format,
+// which can be read by the roaring bitmap of other languages.
+// each chunk is write as the smallest of array, bitmap and run container.
+func WriteRoaring(w io.Writer, s Set) error {
+	keys, conts := roaringChunks(s)
+	_, err := w.Write(appendRoaring(nil, keys, conts))
+	return err
+}
+
+// ReadRoaring read a set in the roaring portable format from r.
+// return a Static set with the biggest item as max
+// if there are at least one item per 32 in average,
+// or a Dynamic set for the sparse one.
+// the items bigger than the maximum of Static and Dynamic can't be read.
+func ReadRoaring(r io.Reader) (Set, error) {
+	keys, conts, err := readRoaring(r)
+	if err != nil {
+		return nil, err
+	}
+	card, last := 0, uint32(0)
+	for _, c := range conts {
+		card += c.card()
+	}
+	if n := len(conts); n > 0 {
+		conts[n-1].iterate(uint32(keys[n-1])<<16, func(x uint32) bool {
+			last = x
+			return true
+		})
+	}
+	if last > maximum {
+		return nil, fmt.Errorf("set: roaring item %d overflow maximum %d", last, maximum)
+	}
+	var p wordSet
+	if uint64(card)*32 >= uint64(last)+1 {
+		var s Static
+		s.OnceInit(int(last))
+		p = &s
+	} else {
+		var s Dynamic
+		s.OnceInit(0)
+		p = &s
+	}
+	for i, c := range conts {
+		base := int(keys[i]) * bitmapWords
+		for j, word := range c.words() {
+			orWord64(p, base+j, word)
+		}
+	}
+	return p, nil
+}
+
+// roaringChunks return the keys and optimized containers of s.
+func roaringChunks(s Set) (keys []uint16, conts []container) {
+	switch ss := s.(type) {
+	case *Roaring:
+		ss.mu.RLock()
+		defer ss.mu.RUnlock()
+		for i, c := range ss.conts {
+			keys = append(keys, ss.keys[i])
+			conts = append(conts, optimize(c.clone()))
+		}
+		return keys, conts
+	}
+	b, ok := toBitSet(s)
+	if !ok {
+		// general set may range out of order.
+		var r Roaring
+		s.Range(func(x uint32) bool {
+			r.Store(x)
+			return true
+		})
+		return roaringChunks(&r)
+	}
+	n := wordCount(b, 32)
+	var words [bitmapWords]uint64
+	for hb := 0; hb*2*bitmapWords < n; hb++ {
+		for j := range words {
+			i := (hb*bitmapWords + j) * 2
+			words[j] = uint64(loadWord(b, i, 32)) | uint64(loadWord(b, i+1, 32))<<32
+		}
+		if c := fromWords(&words); c != nil {
+			keys = append(keys, uint16(hb))
+			conts = append(conts, optimize(c))
+		}
+	}
+	return keys, conts
+}
+
+// appendRoaring append the keys and containers in roaring portable format.
+func appendRoaring(buf []byte, keys []uint16, conts []container) []byte {
+	le := binary.LittleEndian
+	n := len(keys)
+	var runs []byte
+	for i, c := range conts {
+		if _, ok := c.(*runContainer); ok {
+			if runs == nil {
+				runs = make([]byte, (n+7)/8)
+			}
+			runs[i/8] |= 1 << (i % 8)
+		}
+	}
+	start := len(buf)
+	if runs == nil {
+		buf = le.AppendUint32(buf, roaringCookieNoRun)
+		buf = le.AppendUint32(buf, uint32(n))
+	} else {
+		buf = le.AppendUint32(buf, roaringCookie|uint32(n-1)<<16)
+		buf = append(buf, runs...)
+	}
+	for i, c := range conts {
+		buf = le.AppendUint16(buf, keys[i])
+		buf = le.AppendUint16(buf, uint16(c.card()-1))
+	}
+	if runs == nil || n >= roaringNoOffset {
+		offset := len(buf) - start + 4*n
+		for _, c := range conts {
+			buf = le.AppendUint32(buf, uint32(offset))
+			offset += c.sizeInBytes()
+		}
+	}
+	for _, c := range conts {
+		switch cc := c.(type) {
+		case *runContainer:
+			buf = le.AppendUint16(buf, uint16(len(cc.runs)))
+			for _, r := range cc.runs {
+				buf = le.AppendUint16(buf, r.start)
+				buf = le.AppendUint16(buf, r.last-r.start)
+			}
+		case *bitmapContainer:
+			for _, w := range cc.bits {
+				buf = le.AppendUint64(buf, w)
+			}
+		case *arrayContainer:
+			for _, x := range cc.content {
+				buf = le.AppendUint16(buf, x)
+			}
+		}
+	}
+	return buf
+}
+
+// readRoaring read the keys and containers in roaring portable format.
+func readRoaring(r io.Reader) (keys []uint16, conts []container, err error) {
+	le := binary.LittleEndian
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:4]); err != nil {
+		return nil, nil, err
+	}
+	cookie := le.Uint32(b[:4])
+	var n int
+	var runs []byte
+	switch {
+	case cookie == roaringCookieNoRun:
+		if _, err := io.ReadFull(r, b[:4]); err != nil {
+			return nil, nil, unexpectedEOF(err)
+		}
+		if n = int(le.Uint32(b[:4])); n > 1<<16 {
+			return nil, nil, ErrFormat
+		}
+	case cookie&0xFFFF == roaringCookie:
+		n = int(cookie>>16) + 1
+		runs = make([]byte, (n+7)/8)
+		if _, err := io.ReadFull(r, runs); err != nil {
+			return nil, nil, unexpectedEOF(err)
+		}
+	default:
+		return nil, nil, ErrFormat
+	}
+	header := make([]byte, 4*n)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, unexpectedEOF(err)
+	}
+	if runs == nil || n >= roaringNoOffset {
+		// containers are stored in order, offsets are not need.
+		if _, err := io.CopyN(io.Discard, r, int64(4*n)); err != nil {
+			return nil, nil, unexpectedEOF(err)
+		}
+	}
+	keys = make([]uint16, n)
+	conts = make([]container, n)
+	for i := range keys {
+		keys[i] = le.Uint16(header[4*i:])
+		if i > 0 && keys[i] <= keys[i-1] {
+			return nil, nil, ErrFormat
+		}
+		card := int(le.Uint16(header[4*i+2:])) + 1
+		var c container
+		switch {
+		case runs != nil && runs[i/8]&(1<<(i%8)) != 0:
+			c, err = readRunContainer(r, card)
+		case card > arrayMaxSize:
+			c, err = readBitmapContainer(r, card)
+		default:
+			c, err = readArrayContainer(r, card)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		conts[i] = c
+	}
+	return keys, conts, nil
+}
+
+func readRunContainer(r io.Reader, card int) (container, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	data := make([]byte, 4*int(binary.LittleEndian.Uint16(b[:])))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	c := &runContainer{runs: make([]interval16, len(data)/4)}
+	sum := 0
+	for i := range c.runs {
+		start := uint32(binary.LittleEndian.Uint16(data[4*i:]))
+		last := start + uint32(binary.LittleEndian.Uint16(data[4*i+2:]))
+		if last > 1<<16-1 || (i > 0 && start <= uint32(c.runs[i-1].last)) {
+			return nil, ErrFormat
+		}
+		c.runs[i] = interval16{start: uint16(start), last: uint16(last)}
+		sum += int(last-start) + 1
+	}
+	if sum != card {
+		return nil, ErrFormat
+	}
+	return c, nil
+}
+
+func readBitmapContainer(r io.Reader, card int) (container, error) {
+	data := make([]byte, bitmapBytes)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	c := new(bitmapContainer)
+	for i := range c.bits {
+		c.bits[i] = binary.LittleEndian.Uint64(data[8*i:])
+		c.n += bits.OnesCount64(c.bits[i])
+	}
+	if c.n != card {
+		return nil, ErrFormat
+	}
+	return c, nil
+}
+
+func readArrayContainer(r io.Reader, card int) (container, error) {
+	data := make([]byte, 2*card)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	c := newArrayContainer(card)
+	for i := 0; i < card; i++ {
+		x := binary.LittleEndian.Uint16(data[2*i:])
+		if i > 0 && x <= c.content[i-1] {
+			return nil, ErrFormat
+		}
+		c.content = append(c.content, x)
+	}
+	return c, nil
+}
+
+// unexpectedEOF return io.ErrUnexpectedEOF for io.EOF in the middle of data.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,205 @@
+package set_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+// the golden files are written by WriteRoaring and kept to catch a change
+// of output, they are not an independent check of the format,
+// see TestRoaringSpecBytes and TestRoaringReference for that.
+func roaringGolden() []struct {
+	file    string
+	items   func() []uint32
+	dynamic bool
+} {
+	rng := func(lo, hi uint32) []uint32 {
+		var xs []uint32
+		for x := lo; x <= hi; x++ {
+			xs = append(xs, x)
+		}
+		return xs
+	}
+	even := func(hb uint32) []uint32 {
+		var xs []uint32
+		for x := uint32(0); x < 10000; x += 2 {
+			xs = append(xs, hb<<16|x)
+		}
+		return xs
+	}
+	return []struct {
+		file    string
+		items   func() []uint32
+		dynamic bool
+	}{
+		// cookie 12346, array, bitmap and array container.
+		{"norun.bin", func() []uint32 {
+			xs := []uint32{1, 2, 3, 1000, 65535}
+			xs = append(xs, even(1)...)
+			return append(xs, 2<<16|5)
+		}, false},
+		// cookie 12347, run, array, bitmap and full run container with offsets.
+		{"withrun.bin", func() []uint32 {
+			xs := append(rng(10, 1000), rng(2000, 2999)...)
+			xs = append(xs, 1<<16|7, 1<<16|9)
+			xs = append(xs, even(5)...)
+			return append(xs, rng(7<<16, 7<<16|65535)...)
+		}, false},
+		// cookie 12347, less than 4 containers without offsets.
+		{"smallrun.bin", func() []uint32 {
+			return append(rng(0, 99), 3<<16|1, 3<<16|3)
+		}, true},
+		{"empty.bin", func() []uint32 { return nil }, true},
+	}
+}
+
+func TestReadRoaring(t *testing.T) {
+	for _, g := range roaringGolden() {
+		t.Run(g.file, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + g.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := set.ReadRoaring(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := s.(*set.Dynamic); ok != g.dynamic {
+				t.Fatalf("ReadRoaring() type %T", s)
+			}
+			want := g.items()
+			got := set.Items(s)
+			if len(got) != len(want) || (len(want) > 0 && !set.Equal(s, set.FromSorted(want))) {
+				t.Fatalf("ReadRoaring() size %d want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestWriteRoaring(t *testing.T) {
+	for _, g := range roaringGolden() {
+		want, err := os.ReadFile("testdata/" + g.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := g.items()
+		var r set.Roaring
+		set.Adds(&r, items...)
+		for _, s := range []set.Set{set.FromSorted(items), set.NewDynamic(0, items...), &r} {
+			var buf bytes.Buffer
+			if err := set.WriteRoaring(&buf, s); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Fatalf("WriteRoaring(%T) %s not equal golden", s, g.file)
+			}
+		}
+	}
+}
+
+func TestReadRoaringErr(t *testing.T) {
+	data, err := os.ReadFile("testdata/withrun.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.ReadRoaring(bytes.NewReader(nil)); err != io.EOF {
+		t.Fatalf("ReadRoaring(nil) err = %v", err)
+	}
+	for _, n := range []int{3, 6, 30, len(data) - 1} {
+		if _, err := set.ReadRoaring(bytes.NewReader(data[:n])); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("ReadRoaring(data[:%d]) err = %v", n, err)
+		}
+	}
+	bad := append([]byte(nil), data...)
+	bad[0] = 0
+	if _, err := set.ReadRoaring(bytes.NewReader(bad)); !errors.Is(err, set.ErrFormat) {
+		t.Fatalf("ReadRoaring(bad cookie) err = %v", err)
+	}
+	// stop at the end of bitmap.
+	r := bytes.NewReader(append(data, 1, 2, 3))
+	if _, err := set.ReadRoaring(r); err != nil || r.Len() != 3 {
+		t.Fatalf("ReadRoaring() read over %d", 3-r.Len())
+	}
+}
+
+// TestRoaringSpecBytes check bytes assembled field by field from the format spec,
+// https://github.com/RoaringBitmap/RoaringFormatSpec, not by WriteRoaring.
+func TestRoaringSpecBytes(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		data  []byte
+		items []uint32
+	}{
+		{"norun", []byte{
+			0x3a, 0x30, 0, 0, // cookie 12346
+			2, 0, 0, 0, // 2 containers
+			0, 0, 1, 0, // key 0, cardinality 2
+			2, 0, 0, 0, // key 2, cardinality 1
+			24, 0, 0, 0, // offset of container 0
+			28, 0, 0, 0, // offset of container 1
+			1, 0, 5, 0, // array 1, 5
+			2, 0, // array 2<<16|2
+		}, []uint32{1, 5, 2<<16 | 2}},
+		{"run", []byte{
+			0x3b, 0x30, 1, 0, // cookie 12347, 2 containers
+			0x01,        // container 0 is run
+			0, 0, 10, 0, // key 0, cardinality 11
+			1, 0, 0, 0, // key 1, cardinality 1
+			1, 0, 10, 0, 10, 0, // 1 run, start 10, length 11
+			7, 0, // array 1<<16|7
+		}, []uint32{10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 1<<16 | 7}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := set.ReadRoaring(bytes.NewReader(c.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !set.Equal(s, set.FromSorted(c.items)) || set.Size(s) != len(c.items) {
+				t.Fatalf("ReadRoaring() = %v", s)
+			}
+			var buf bytes.Buffer
+			if err := set.WriteRoaring(&buf, s); err != nil || !bytes.Equal(buf.Bytes(), c.data) {
+				t.Fatalf("WriteRoaring() = % x %v", buf.Bytes(), err)
+			}
+		})
+	}
+}
+
+// TestRoaringReference read the test files of the format spec repository,
+// testdata/bitmapwithoutruns.bin and testdata/bitmapwithruns.bin
+// written by the Java implementation, the items are those checked by
+// CRoaring tests/format_portability_unit.c.
+// the files are copy from RoaringBitmap/RoaringFormatSpec testdata
+// as vendored by github.com/RoaringBitmap/roaring v0.4.23 (Apache-2.0).
+func TestRoaringReference(t *testing.T) {
+	var want []uint32
+	for k := uint32(0); k < 100000; k += 1000 {
+		want = append(want, k)
+	}
+	for k := uint32(100000); k < 200000; k++ {
+		want = append(want, 3*k)
+	}
+	for k := uint32(700000); k < 800000; k++ {
+		want = append(want, k)
+	}
+	for _, file := range []string{"bitmapwithoutruns.bin", "bitmapwithruns.bin"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := set.ReadRoaring(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !set.Equal(s, set.FromSorted(want)) || set.Size(s) != len(want) {
+				t.Fatalf("ReadRoaring() size %d want %d", set.Size(s), len(want))
+			}
+		})
+	}
+}
@@ -5,7 +5,9 @@ package set
 import (
 	"bytes"
 	"fmt"
+	"math/bits"
 	"reflect"
+	"sort"
 	"sync/atomic"
 	"unsafe"
 )
@@ -15,6 +17,12 @@ var (
 	staticType = reflect.TypeOf(new(Static))
 	// reflact.typeof Dynamic
 	dynamicType = reflect.TypeOf(new(Dynamic))
+	// reflact.typeof Roaring
+	roaringType = reflect.TypeOf(new(Roaring))
+	// reflact.typeof Snapshot
+	snapshotType = reflect.TypeOf(new(Snapshot))
+	// reflact.typeof Persistent
+	persistentType = reflect.TypeOf(new(Persistent))
 )
 
 // String returns the set as a string of the form "{1 2 3}".
@@ -58,7 +66,7 @@ func New(args ...uint32) Set {
 func NewStatic(cap int, args ...uint32) Set {
 	var s Static
 	s.OnceInit(cap)
-	Adds(&s, args...)
+	s.StoreMany(args)
 	return &s
 }
 
@@ -67,7 +75,53 @@ func NewStatic(cap int, args ...uint32) Set {
 func NewDynamic(cap int, args ...uint32) Set {
 	var s Dynamic
 	s.OnceInit(cap)
-	Adds(&s, args...)
+	s.StoreMany(args)
+	return &s
+}
+
+// FromSorted return a Static set with the ascending items xs,
+// the max of set is the last item of xs.
+// each word is build locally and publish with one atomic CAS.
+// if xs is not sorted, the max of set is the biggest item,
+// and xs is still stored, with more atomic op.
+// time complexity: O(len(xs))
+func FromSorted(xs []uint32) *Static {
+	var s Static
+	if len(xs) > 0 {
+		m := xs[len(xs)-1]
+		for i := 1; i < len(xs); i++ {
+			if xs[i] < xs[i-1] {
+				// not sorted, find the biggest item.
+				for _, x := range xs {
+					if x > m {
+						m = x
+					}
+				}
+				break
+			}
+		}
+		s.OnceInit(int(m))
+	}
+	s.StoreMany(xs)
+	return &s
+}
+
+// FromWords return a Static set with the bitmap words,
+// bit j of words[i] is item 64*i+j.
+// if max<1, max is the last bit of words,
+// the items bigger than max are dropped.
+func FromWords(words []uint64, max int) *Static {
+	if max < 1 {
+		max = len(words)*64 - 1
+	}
+	var s Static
+	s.OnceInit(max)
+	m := s.getMax()
+	for i, w := range words {
+		lo, hi := 2*i, 2*i+1
+		s.orWord(lo, uint32(w)&validMask(32, m, lo))
+		s.orWord(hi, uint32(w>>32)&validMask(32, m, hi))
+	}
 	return &s
 }
 
@@ -81,6 +135,8 @@ const (
 const (
 	rtStatic reflactType = iota + 1
 	rtDynamic
+	rtRoaring
+	rtPersistent
 	// rtOption
 	rtOther
 
@@ -91,6 +147,10 @@ const (
 	rtDynamicStatic  = rtDynamic<<bit | rtStatic
 	rtDynamicDynamic = rtDynamic<<bit | rtDynamic
 
+	rtRoaringRoaring = rtRoaring<<bit | rtRoaring
+
+	rtPersistentPersistent = rtPersistent<<bit | rtPersistent
+
 	rtOtherSame = rtOther<<bit | rtOther
 )
 
@@ -172,6 +232,16 @@ var readOnlyCB map[reflactType]cbFunc = map[reflactType]cbFunc{
 		sameType(cx, yy, &p)
 		return &p
 	},
+	rtRoaringRoaring: func(x, y Set, flag opFlag, sameType opSameType) Set {
+		xx := x.(*Roaring)
+		yy := y.(*Roaring)
+		return roaringOperation(xx, yy, flag)
+	},
+	rtPersistentPersistent: func(x, y Set, flag opFlag, sameType opSameType) Set {
+		xx := x.(*Persistent)
+		yy := y.(*Persistent)
+		return persistentOperation(xx, yy, flag)
+	},
 }
 
 // get s,t reflact type, return two type relation.
@@ -184,6 +254,10 @@ func getReflectType(s, t Set) (r reflactType) {
 		ss = rtStatic
 	case dynamicType:
 		ss = rtDynamic
+	case roaringType:
+		ss = rtRoaring
+	case persistentType:
+		ss = rtPersistent
 	// case OptionType:
 	// 	ss = rtOption
 	default:
@@ -195,6 +269,10 @@ func getReflectType(s, t Set) (r reflactType) {
 		tt = rtStatic
 	case dynamicType:
 		tt = rtDynamic
+	case roaringType:
+		tt = rtRoaring
+	case persistentType:
+		tt = rtPersistent
 	// case OptionType:
 	// 	tt = rtOption
 	default:
@@ -211,7 +289,12 @@ func operation(x, y Set, flag opFlag, sameType opSameType, general opGeneral) Se
 	if f, ok := readOnlyCB[r]; ok {
 		return f(x, y, flag, sameType)
 	}
-	if r == rtOtherSame {
+	// a Persistent hold items to 1<<32-1, return Persistent.
+	if r>>bit == rtPersistent || r&(1<<bit-1) == rtPersistent {
+		return persistentOperation(ToPersistent(x), ToPersistent(y), flag)
+	}
+	// snapshot is immutable, return Static.
+	if r == rtOtherSame && reflect.TypeOf(x) != snapshotType {
 		typ := reflect.TypeOf(x)
 		p := reflect.New(typ.Elem()).Interface().(Set)
 		return general(x, y, p)
@@ -223,6 +306,7 @@ func operation(x, y Set, flag opFlag, sameType opSameType, general opGeneral) Se
 
 // Union return the union set of s and t.
 // if set s and t is same type,return the same type
+// if either is Persistent,return the Persistent type
 // if not the same,return the Static type
 //
 // worst time complexity: O(N)
@@ -234,6 +318,7 @@ func Union(s, t Set) Set {
 // Intersect return the intersection set of s and t
 // item in s and t
 // if set s and t is same type,return the same type
+// if either is Persistent,return the Persistent type
 // if not the same,return the Static type
 //
 // worst time complexity: O(N)
@@ -245,6 +330,7 @@ func Intersect(s, t Set) Set {
 // Difference return the difference set of s and t
 // item in s and not in t
 // if set s and t is same type,return the same type
+// if either is Persistent,return the Persistent type
 // if not the same,return the Static type
 //
 // worst time complexity: O(N)
@@ -256,6 +342,7 @@ func Difference(s, t Set) Set {
 // Complement return the complement set of s and t
 // item in s but not in t, and not in s but in t.
 // if set s and t is same type,return the same type
+// if either is Persistent,return the Persistent type
 // if not the same,return the Static type
 //
 // worst time complexity: O(N)
@@ -264,6 +351,339 @@ func Complement(s, t Set) Set {
 	return operation(s, t, opComplement, sameTypeComplement, generalComplement)
 }
 
+// UnionSize return the number of items in s or in t,
+// without materialize the union set.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func UnionSize(s, t Set) int {
+	return operationSize(s, t, opUnion)
+}
+
+// IntersectSize return the number of items in s and in t,
+// without materialize the intersection set.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func IntersectSize(s, t Set) int {
+	return operationSize(s, t, opIntersect)
+}
+
+// DifferenceSize return the number of items in s but not in t,
+// without materialize the difference set.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func DifferenceSize(s, t Set) int {
+	return operationSize(s, t, opDifference)
+}
+
+// SymmetricDifferenceSize return the number of items in s or in t but not both,
+// without materialize the complement set.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func SymmetricDifferenceSize(s, t Set) int {
+	return operationSize(s, t, opComplement)
+}
+
+// operationSize return the size of the flag operation of s and t.
+// Static and Dynamic are counted with popcount of each word,
+// mixed Static and Dynamic are compared without convert.
+func operationSize(s, t Set, flag opFlag) int {
+	ss, sok := toBitSet(s)
+	tt, tok := toBitSet(t)
+	if !sok || !tok {
+		inter := 0
+		s.Range(func(x uint32) bool {
+			if t.Load(x) {
+				inter += 1
+			}
+			return true
+		})
+		switch flag {
+		case opUnion:
+			return Size(s) + Size(t) - inter
+		case opIntersect:
+			return inter
+		case opDifference:
+			return Size(s) - inter
+		}
+		return Size(s) + Size(t) - 2*inter
+	}
+	w := opWidth(ss, tt)
+	slen, tlen := wordCount(ss, w), wordCount(tt, w)
+	var n int
+	switch flag {
+	case opUnion, opComplement:
+		n = max(slen, tlen)
+	case opIntersect:
+		n = min(slen, tlen)
+	case opDifference:
+		n = slen
+	}
+	sum := 0
+	for i := 0; i < n; i++ {
+		sum += bits.OnesCount32(opWord(flag, loadWord(ss, i, w), loadWord(tt, i, w)))
+	}
+	return sum
+}
+
+// Not return the items in [0,max] but not in s,
+// max is the max of s,the result is the same type of s.
+// if s is not Static, Dynamic or Roaring,
+// s is converted to Static with the biggest item as max.
+//
+// time complexity: O(max/32)
+func Not(s Set) Set {
+	switch ss := s.(type) {
+	case *Static:
+		return ss.Not()
+	case *Dynamic:
+		return ss.Not()
+	case *Roaring:
+		return ss.Not()
+	}
+	return ToStatic(s).Not()
+}
+
+// Full return a Static set hold all items in [0,max].
+// if max<1, will use 256.
+func Full(max int) Set {
+	var s Static
+	s.OnceInit(max)
+	s.AddRange(0, s.getMax())
+	return &s
+}
+
+// FullDynamic return a Dynamic set hold all items in [0,max].
+// if max<1, hold all items to maximum,
+// it allocate about 128MB, give a max if the set is not so big.
+func FullDynamic(max int) Set {
+	var s Dynamic
+	s.OnceInit(max)
+	s.AddRange(0, s.getMax())
+	return &s
+}
+
+// operate return the flag operation of s and t.
+func operate(s, t Set, flag opFlag) Set {
+	switch flag {
+	case opUnion:
+		return Union(s, t)
+	case opIntersect:
+		return Intersect(s, t)
+	case opDifference:
+		return Difference(s, t)
+	}
+	return Complement(s, t)
+}
+
+// UnionInto set dst to the union set of s and t, return dst.
+// if dst,s and t are Static or Dynamic,
+// dst is update word by word without allocate,
+// the items bigger than dst's max are dropped.
+// dst Persistent or Snapshot is immutable, it is left unchanged,
+// use the result of Union instead.
+//
+// time complexity: O(N/32)
+func UnionInto(dst, s, t Set) Set {
+	return operationInto(dst, s, t, opUnion)
+}
+
+// IntersectInto set dst to the intersection set of s and t, return dst.
+// if dst,s and t are Static or Dynamic,
+// dst is update word by word without allocate.
+// dst Persistent or Snapshot is immutable, it is left unchanged,
+// use the result of Intersect instead.
+//
+// time complexity: O(N/32)
+func IntersectInto(dst, s, t Set) Set {
+	return operationInto(dst, s, t, opIntersect)
+}
+
+// DifferenceInto set dst to the difference set of s and t, return dst.
+// if dst,s and t are Static or Dynamic,
+// dst is update word by word without allocate.
+// dst Persistent or Snapshot is immutable, it is left unchanged,
+// use the result of Difference instead.
+//
+// time complexity: O(N/32)
+func DifferenceInto(dst, s, t Set) Set {
+	return operationInto(dst, s, t, opDifference)
+}
+
+// ComplementInto set dst to the complement set of s and t, return dst.
+// if dst,s and t are Static or Dynamic,
+// dst is update word by word without allocate.
+// dst Persistent or Snapshot is immutable, it is left unchanged,
+// use the result of Complement instead.
+//
+// time complexity: O(N/32)
+func ComplementInto(dst, s, t Set) Set {
+	return operationInto(dst, s, t, opComplement)
+}
+
+// UnionAll return the union set of all sets.
+// if all sets are Static or Dynamic, it's computed in one pass word by word,
+// return Dynamic if all sets are Dynamic, or return Static.
+// if no sets,return an empty Dynamic set.
+//
+// time complexity: O(K*N/32)
+func UnionAll(sets ...Set) Set {
+	if len(sets) == 0 {
+		return NewDynamic(0)
+	}
+	words, maxes, dynamic, ok := allBitSet(sets)
+	if !ok {
+		p := Copy(sets[0])
+		for _, t := range sets[1:] {
+			p = Union(p, t)
+		}
+		return p
+	}
+	// a Dynamic without max make smax maximum, the result grow on demand.
+	var smax uint32
+	for _, m := range maxes {
+		if m > smax {
+			smax = m
+		}
+	}
+	p := newWordSet(dynamic, smax)
+	w := p.wordBits()
+	n := 0
+	for _, s := range words {
+		n = max(n, wordCount(s, w))
+	}
+	p.updateWords(0, n-1, func(idx int, item uint32) uint32 {
+		for _, s := range words {
+			item |= loadWord(s, idx, w)
+		}
+		return item
+	})
+	return p
+}
+
+// IntersectAll return the intersection set of all sets.
+// the smallest set is processed first,
+// and stop as soon as the result is empty.
+// if all sets are Static or Dynamic, it's computed in one pass word by word,
+// return Dynamic if all sets are Dynamic, or return Static.
+// if no sets,return an empty Dynamic set.
+//
+// time complexity: O(K*N/32)
+func IntersectAll(sets ...Set) Set {
+	if len(sets) == 0 {
+		return NewDynamic(0)
+	}
+	// smallest first
+	sorted := make([]Set, len(sets))
+	copy(sorted, sets)
+	sizes := make([]int, len(sorted))
+	for i, s := range sorted {
+		sizes[i] = Size(s)
+	}
+	sort.Sort(bySize{sorted, sizes})
+
+	words, maxes, dynamic, ok := allBitSet(sorted)
+	if !ok {
+		p := Copy(sorted[0])
+		for _, t := range sorted[1:] {
+			if Null(p) {
+				break
+			}
+			p = Intersect(p, t)
+		}
+		return p
+	}
+	smax := maxes[0]
+	for _, m := range maxes {
+		if m < smax {
+			smax = m
+		}
+	}
+	p := newWordSet(dynamic, smax)
+	if sizes[0] == 0 {
+		return p
+	}
+	w := p.wordBits()
+	n := wordCount(words[0], w)
+	for _, s := range words[1:] {
+		n = min(n, wordCount(s, w))
+	}
+	p.updateWords(0, n-1, func(idx int, item uint32) uint32 {
+		item = loadWord(words[0], idx, w)
+		for _, s := range words[1:] {
+			if item == 0 {
+				break
+			}
+			item &= loadWord(s, idx, w)
+		}
+		return item
+	})
+	return p
+}
+
+// bySize sort sets by size.
+type bySize struct {
+	sets  []Set
+	sizes []int
+}
+
+func (b bySize) Len() int           { return len(b.sets) }
+func (b bySize) Less(i, j int) bool { return b.sizes[i] < b.sizes[j] }
+func (b bySize) Swap(i, j int) {
+	b.sets[i], b.sets[j] = b.sets[j], b.sets[i]
+	b.sizes[i], b.sizes[j] = b.sizes[j], b.sizes[i]
+}
+
+// allBitSet return the words view of sets and the max of each set,
+// the max of set, not of its words, a Dynamic without max is maximum.
+// dynamic report whether all sets are Dynamic,
+// ok is false if any set is not Static or Dynamic.
+func allBitSet(sets []Set) (words []bitSet, maxes []uint32, dynamic, ok bool) {
+	words = make([]bitSet, len(sets))
+	maxes = make([]uint32, len(sets))
+	dynamic = true
+	for i, s := range sets {
+		b, ok := toBitSet(s)
+		if !ok {
+			return nil, nil, false, false
+		}
+		switch ss := s.(type) {
+		case *Static:
+			maxes[i] = ss.getMax()
+		case *Dynamic:
+			maxes[i] = ss.getMax()
+		case *Snapshot:
+			maxes[i] = ss.max
+		}
+		if b.wordBits() == 32 {
+			// Static or snapshot of Static.
+			dynamic = false
+		}
+		words[i] = b
+	}
+	return words, maxes, dynamic, true
+}
+
+// newWordSet return an empty set hold items <= max,
+// Dynamic if dynamic, or Static.
+func newWordSet(dynamic bool, max uint32) wordSet {
+	if dynamic {
+		var p Dynamic
+		if max >= maximum {
+			// grow on demand
+			max = 0
+		}
+		p.OnceInit(int(max))
+		return &p
+	}
+	var p Static
+	p.OnceInit(int(max))
+	return &p
+}
+
 // Equal return set if equal, s <==> t
 func Equal(s, t Set) bool {
 	r := getReflectType(s, t)
@@ -286,12 +706,123 @@ func Equal(s, t Set) bool {
 		tt := t.(*Static)
 		iss := trendsToStatic(ss)
 		return sameTypeEqual(iss, tt)
+	case rtRoaringRoaring:
+		return roaringEqual(s.(*Roaring), t.(*Roaring))
+	case rtPersistentPersistent:
+		return s.(*Persistent).Equal(t.(*Persistent))
 	case rtOtherSame:
 	}
 	return generalEqual(s, t)
 }
 
+// IsSubset report whether every item of s is in t, s ⊆ t.
+// Static and Dynamic are compared word by word,
+// and return on the first word which has item only in s.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func IsSubset(s, t Set) bool {
+	subset, _ := subsetOf(s, t, false)
+	return subset
+}
+
+// IsSuperset report whether every item of t is in s, s ⊇ t.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func IsSuperset(s, t Set) bool {
+	return IsSubset(t, s)
+}
+
+// IsProperSubset report whether s ⊆ t and s != t.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func IsProperSubset(s, t Set) bool {
+	subset, proper := subsetOf(s, t, true)
+	return subset && proper
+}
+
+// Disjoint report whether s and t has no item in common.
+// Static and Dynamic are compared word by word,
+// and return on the first word which has common item.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func Disjoint(s, t Set) bool {
+	if getReflectType(s, t) == rtPersistentPersistent {
+		return s.(*Persistent).Disjoint(t.(*Persistent))
+	}
+	ss, sok := toBitSet(s)
+	tt, tok := toBitSet(t)
+	if !sok || !tok {
+		var disjoint = true
+		s.Range(func(x uint32) bool {
+			disjoint = !t.Load(x)
+			return disjoint
+		})
+		return disjoint
+	}
+	w := opWidth(ss, tt)
+	n := min(wordCount(ss, w), wordCount(tt, w))
+	for i := 0; i < n; i++ {
+		if loadWord(ss, i, w)&loadWord(tt, i, w) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersects report whether s and t has at least one item in common.
+//
+// worst time complexity: O(N)
+// best  time complexity: O(N/32)
+func Intersects(s, t Set) bool {
+	return !Disjoint(s, t)
+}
+
+// subsetOf report whether s ⊆ t,
+// and if checkProper, whether t has item not in s.
+func subsetOf(s, t Set, checkProper bool) (subset, proper bool) {
+	if getReflectType(s, t) == rtPersistentPersistent {
+		ps, pt := s.(*Persistent), t.(*Persistent)
+		subset = ps.IsSubset(pt)
+		return subset, subset && ps.Len() < pt.Len()
+	}
+	ss, sok := toBitSet(s)
+	tt, tok := toBitSet(t)
+	if !sok || !tok {
+		subset = true
+		s.Range(func(x uint32) bool {
+			subset = t.Load(x)
+			return subset
+		})
+		if subset && checkProper {
+			proper = Size(s) < Size(t)
+		}
+		return subset, proper
+	}
+	w := opWidth(ss, tt)
+	n := wordCount(ss, w)
+	if checkProper {
+		n = max(n, wordCount(tt, w))
+	}
+	for i := 0; i < n; i++ {
+		sw, tw := loadWord(ss, i, w), loadWord(tt, i, w)
+		if sw&^tw != 0 {
+			return false, false
+		}
+		if tw&^sw != 0 {
+			proper = true
+		}
+	}
+	return true, proper
+}
+
 // Copy return a copy of s
+// the copy of a Snapshot is a mutable Static or Dynamic.
+// Static.Clone share the words and copy lazily, cheaper for a big set.
+// Persistent is immutable, return itself.
 //
 // worst time complexity: O(N)
 // best  time complexity: O(N/32)
@@ -310,6 +841,19 @@ func Copy(s Set) Set {
 		p.OnceInit(int(ss.getMax()))
 		sameTypeCopy(ss, &p)
 		return &p
+	case roaringType:
+		return roaringCopy(s.(*Roaring))
+	case snapshotType:
+		// a mutable set of the same words.
+		b := s.(*Snapshot).bits
+		p := newWordSet(b.wordBits() == 16, s.(*Snapshot).max)
+		w := p.wordBits()
+		p.updateWords(0, wordCount(b, w)-1, func(idx int, item uint32) uint32 {
+			return loadWord(b, idx, w)
+		})
+		return p
+	case persistentType:
+		return s
 	}
 	typ := reflect.TypeOf(s)
 	p := reflect.New(typ.Elem()).Interface().(Set)
@@ -317,31 +861,73 @@ func Copy(s Set) Set {
 }
 
 // Items return all element in the set
+// the slice is allocate with Size of the set.
 // time complexity: O(N)
 func Items(s Set) []uint32 {
-	sum := 0
-	var slen uint32 = 0
-	r := reflect.TypeOf(s)
-	switch r {
-	case staticType:
-		ss := s.(*Static)
-		slen = ss.getLen()
-	case dynamicType:
-		ss := s.(*Dynamic)
-		slen = ss.getEntry().getLen()
-	// case OptionType:
-	// 	ss := s.(*Option)
-	// 	slen = ss.getEntry().getLen()
-	default:
-		slen = initSize
+	return AppendItems(make([]uint32, 0, Size(s)), s)
+}
+
+// AppendItems append all element in the set to dst in ascending order,
+// and return the extended slice.
+// it not allocate if dst has enough space,
+// so that dst can be reused between calls.
+// time complexity: O(N)
+func AppendItems(dst []uint32, s Set) []uint32 {
+	b, ok := toBitSet(s)
+	if !ok {
+		return appendRange(dst, s)
+	}
+	w := b.wordBits()
+	slen := int(b.getLen())
+	for i := 0; i < slen; i++ {
+		for item := b.load(i); item != 0; item &= item - 1 {
+			dst = append(dst, uint32(i)*w+uint32(bits.TrailingZeros32(item)))
+		}
+	}
+	return dst
+}
+
+// CopyWords copy the bitmap words of the set to dst,
+// bit j of dst[i] is item 64*i+j, the items not fit in dst are ignore.
+// return the number of words copied, min(len(dst), words of the set).
+// time complexity: O(N/64)
+func CopyWords(dst []uint64, s Set) int {
+	b, ok := toBitSet(s)
+	if !ok {
+		return copyRange(dst, s)
+	}
+	n := min(len(dst), wordCount(b, 64))
+	for i := 0; i < n; i++ {
+		dst[i] = uint64(loadWord(b, 2*i, 32)) | uint64(loadWord(b, 2*i+1, 32))<<32
 	}
-	array := make([]uint32, 0, slen*32)
+	return n
+}
+
+// appendRange append the items of s to dst by Range.
+// keep the closure out of AppendItems, or dst escape to heap.
+func appendRange(dst []uint32, s Set) []uint32 {
 	s.Range(func(x uint32) bool {
-		array = append(array, x)
-		sum += 1
+		dst = append(dst, x)
 		return true
 	})
-	return array[:sum]
+	return dst
+}
+
+// copyRange copy the items of s to dst words by Range.
+func copyRange(dst []uint64, s Set) int {
+	n := 0
+	for i := range dst {
+		dst[i] = 0
+	}
+	s.Range(func(x uint32) bool {
+		// Range of general set may not in order.
+		if i := int(x / 64); i < len(dst) {
+			dst[i] |= 1 << (x % 64)
+			n = max(n, i+1)
+		}
+		return true
+	})
+	return n
 }
 
 // use for sameType operation
@@ -707,6 +1293,8 @@ func ToStatic(s Set) *Static {
 	case dynamicType:
 		ss := s.(*Dynamic)
 		return trendsToStatic(ss)
+	case persistentType:
+		return s.(*Persistent).ToStatic()
 	// case OptionType:
 	// 	ss := s.(*Option)
 	// 	return ss.Static()
@@ -738,6 +1326,8 @@ func ToDynamic(s Set) *Dynamic {
 		return staticToTrends(ss)
 	case dynamicType:
 		return s.(*Dynamic)
+	case persistentType:
+		return s.(*Persistent).ToDynamic()
 	default:
 		var ss Dynamic
 		ss.OnceInit(0)
@@ -919,10 +1509,7 @@ func Size(s Set) int {
 		ss := s.(*Static)
 		size = atomic.LoadUint32(&ss.count)
 		if size == 0 {
-			ss.Range(func(x uint32) bool {
-				size += 1
-				return true
-			})
+			size = uint32(popCount(ss))
 			atomic.CompareAndSwapUint32(&ss.count, 0, size)
 		}
 	case dynamicType:
@@ -930,12 +1517,15 @@ func Size(s Set) int {
 		e := ss.getEntry()
 		size = atomic.LoadUint32(&e.count)
 		if size == 0 {
-			ss.Range(func(x uint32) bool {
-				size += 1
-				return true
-			})
+			size = uint32(popCount(e))
 			atomic.CompareAndSwapUint32(&e.count, 0, size)
 		}
+	case roaringType:
+		size = uint32(s.(*Roaring).size())
+	case snapshotType:
+		size = uint32(s.(*Snapshot).Len())
+	case persistentType:
+		size = uint32(s.(*Persistent).Len())
 	default:
 		s.Range(func(x uint32) bool {
 			size += 1
@@ -946,6 +1536,7 @@ func Size(s Set) int {
 }
 
 // Clear remove all elements from the set
+// Persistent or Snapshot is immutable, it is left unchanged.
 // time complexity: O(N/32)
 func Clear(s Set) {
 	r := reflect.TypeOf(s)
@@ -960,13 +1551,23 @@ func Clear(s Set) {
 		atomic.CompareAndSwapUint32(&ss.len, slen, 0)
 	case dynamicType:
 		ss := s.(*Dynamic)
+		max := ss.getMax()
+		if max >= maximum {
+			// a growing set, not preallocate the whole range.
+			max = 0
+		}
 		for {
 			n := ss.getEntry()
-			ne := newNode(ss.getMax())
+			ne := newNode(max)
+			if atomic.LoadUint32(&ss.ranked) == 1 {
+				ne.rank = unsafe.Pointer(newRankIndex(ne))
+			}
 			if atomic.CompareAndSwapPointer(&ss.node, unsafe.Pointer(n), unsafe.Pointer(ne)) {
 				break
 			}
 		}
+	case roaringType:
+		s.(*Roaring).clear()
 	default:
 		s.Range(func(x uint32) bool {
 			s.Delete(x)
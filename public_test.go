@@ -2,7 +2,10 @@ package set_test
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/min1324/set"
@@ -119,6 +122,10 @@ func opArgsTest(t *testing.T, r opArgs, f opTestFunc) {
 			name: "getTrends",
 			val:  getDynamic(cap1, x1, y1),
 		},
+		{
+			name: "getRoaring",
+			val:  getRoaring(cap1, x1, y1),
+		},
 
 		{
 			name: "getMutexSet",
@@ -134,6 +141,10 @@ func opArgsTest(t *testing.T, r opArgs, f opTestFunc) {
 			name: "getTrends",
 			val:  getDynamic(cap2, x2, y2),
 		},
+		{
+			name: "getRoaring",
+			val:  getRoaring(cap2, x2, y2),
+		},
 		{
 			name: "getMutexSet",
 			val:  getMutexSet(cap2, x2, y2),
@@ -594,3 +605,504 @@ func maxmin(x, y int) (max, min int) {
 	}
 	return y, x
 }
+
+type withSet interface {
+	set.Set
+	UnionWith(t set.Set)
+	IntersectWith(t set.Set)
+	DifferenceWith(t set.Set)
+	SymmetricDifferenceWith(t set.Set)
+}
+
+func TestOperationWith(t *testing.T) {
+	ops := []struct {
+		name string
+		with func(s withSet, t set.Set)
+		want opFunc
+	}{
+		{"UnionWith", withSet.UnionWith, set.Union},
+		{"IntersectWith", withSet.IntersectWith, set.Intersect},
+		{"DifferenceWith", withSet.DifferenceWith, set.Difference},
+		{"SymmetricDifferenceWith", withSet.SymmetricDifferenceWith, set.Complement},
+	}
+	recv := map[string]func() withSet{
+		"getStatic":  func() withSet { return getStatic(1000, 0, 500) },
+		"getDynamic": func() withSet { return getDynamic(0, 0, 500) },
+	}
+	args := map[string]func() set.Set{
+		"getStatic":   func() set.Set { return getStatic(1000, 300, 900) },
+		"getDynamic":  func() set.Set { return getDynamic(0, 300, 900) },
+		"getRoaring":  func() set.Set { return getRoaring(1000, 300, 900) },
+		"getMutexSet": func() set.Set { return getMutexSet(1000, 300, 900) },
+	}
+	for _, op := range ops {
+		for rn, r := range recv {
+			for an, a := range args {
+				t.Run(op.name+"/"+rn+"+"+an, func(t *testing.T) {
+					s, x := r(), a()
+					want := op.want(set.Copy(s), x)
+					op.with(s, x)
+					if !reflect.DeepEqual(set.Items(s), set.Items(want)) {
+						t.Fatalf("got %v, want %v", s, want)
+					}
+					if set.Size(s) != set.Size(want) {
+						t.Fatalf("Size() = %d, want %d", set.Size(s), set.Size(want))
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestOperationInto(t *testing.T) {
+	ops := []struct {
+		name string
+		into func(dst, s, t set.Set) set.Set
+		want opFunc
+	}{
+		{"UnionInto", set.UnionInto, set.Union},
+		{"IntersectInto", set.IntersectInto, set.Intersect},
+		{"DifferenceInto", set.DifferenceInto, set.Difference},
+		{"ComplementInto", set.ComplementInto, set.Complement},
+	}
+	sets := map[string]func(m, n int) set.Set{
+		"getStatic":  func(m, n int) set.Set { return getStatic(2000, m, n) },
+		"getDynamic": func(m, n int) set.Set { return getDynamic(0, m, n) },
+		"getRoaring": func(m, n int) set.Set { return getRoaring(0, m, n) },
+	}
+	for _, op := range ops {
+		for dn, d := range sets {
+			for sn, s := range sets {
+				t.Run(op.name+"/"+dn+"+"+sn, func(t *testing.T) {
+					x, y := s(0, 500), s(300, 900)
+					want := op.want(x, y)
+					// dst hold old items
+					dst := d(400, 1500)
+					if got := op.into(dst, x, y); got != dst {
+						t.Fatalf("not return dst")
+					}
+					if !reflect.DeepEqual(set.Items(dst), set.Items(want)) {
+						t.Fatalf("got %v, want %v", dst, want)
+					}
+					if set.Size(dst) != set.Size(want) {
+						t.Fatalf("Size() = %d, want %d", set.Size(dst), set.Size(want))
+					}
+					// dst is s
+					op.into(x, x, y)
+					if !set.Equal(x, want) {
+						t.Fatalf("alias got %v, want %v", x, want)
+					}
+				})
+			}
+		}
+	}
+	// drop the items bigger than max
+	dst := getStatic(100, 0, 0)
+	set.UnionInto(dst, getStatic(1000, 50, 150), getDynamic(0, 90, 120))
+	if got, want := set.Items(dst), set.Items(getStatic(100, 50, 101)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestOperationAll(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	newSets := map[string]func(i int) set.Set{
+		"Static":  func(i int) set.Set { return set.NewStatic(2000 + i) },
+		"Dynamic": func(i int) set.Set { return set.NewDynamic(0) },
+		"Mixed": func(i int) set.Set {
+			if i%2 == 0 {
+				return set.NewStatic(2000)
+			}
+			return set.NewDynamic(0)
+		},
+		"Roaring": func(i int) set.Set {
+			if i%3 == 0 {
+				return new(set.Roaring)
+			}
+			return set.NewStatic(2000)
+		},
+	}
+	for name, newSet := range newSets {
+		t.Run(name, func(t *testing.T) {
+			sets := make([]set.Set, 20)
+			for i := range sets {
+				sets[i] = newSet(i)
+				for j := 0; j < 1800; j++ {
+					sets[i].Store(uint32(r.Intn(2000)))
+				}
+			}
+			union, inter := sets[0], sets[0]
+			for _, s := range sets[1:] {
+				union = set.Union(union, s)
+				inter = set.Intersect(inter, s)
+			}
+			if got := set.UnionAll(sets...); !reflect.DeepEqual(set.Items(got), set.Items(union)) {
+				t.Fatalf("UnionAll() = %v, want %v", got, union)
+			}
+			if got := set.IntersectAll(sets...); !reflect.DeepEqual(set.Items(got), set.Items(inter)) {
+				t.Fatalf("IntersectAll() = %v, want %v", got, inter)
+			}
+			// empty set stop early
+			sets = append(sets, newSet(0))
+			if got := set.IntersectAll(sets...); !set.Null(got) {
+				t.Fatalf("IntersectAll() = %v, want {}", got)
+			}
+		})
+	}
+	if !set.Null(set.UnionAll()) || !set.Null(set.IntersectAll()) {
+		t.Fatalf("no sets not empty")
+	}
+}
+
+// TestAllMax the result of UnionAll and IntersectAll hold the items
+// beyond the operands' capacity as Union and Intersect.
+func TestAllMax(t *testing.T) {
+	const x = 100000
+	all := map[string]func(sets ...set.Set) set.Set{
+		"UnionAll":     set.UnionAll,
+		"IntersectAll": set.IntersectAll,
+	}
+	for name, f := range all {
+		p := f(set.NewDynamic(0, 0, 1, 2), set.NewDynamic(0, 0, 3))
+		if !p.Store(x) || !p.Load(x) {
+			t.Fatalf("%s(Dynamic).Store(%d) fail", name, x)
+		}
+		s := set.NewDynamic(0, 0, 5)
+		p = f(s.(*set.Dynamic).Snapshot(), set.NewDynamic(0, 0))
+		if !p.Store(x) {
+			t.Fatalf("%s(Snapshot).Store(%d) fail", name, x)
+		}
+		p = f(set.NewStatic(x+10, 0), set.NewStatic(x+20, 0))
+		if !p.Store(x) {
+			t.Fatalf("%s(Static).Store(%d) fail", name, x)
+		}
+	}
+	if p := set.IntersectAll(set.NewStatic(50, 0), set.NewDynamic(0, 0)); p.Store(51) {
+		t.Fatalf("IntersectAll() store beyond the smallest max")
+	}
+}
+
+func TestRelation(t *testing.T) {
+	type want struct {
+		subset, superset, proper, disjoint bool
+	}
+	cases := []struct {
+		name   string
+		s, t   [2]int
+		expect want
+	}{
+		{"subset", [2]int{10, 50}, [2]int{0, 100}, want{true, false, true, false}},
+		{"equal", [2]int{0, 100}, [2]int{0, 100}, want{true, true, false, false}},
+		{"superset", [2]int{0, 100}, [2]int{40, 60}, want{false, true, false, false}},
+		{"overlap", [2]int{0, 60}, [2]int{40, 100}, want{false, false, false, false}},
+		{"disjoint", [2]int{0, 40}, [2]int{60, 100}, want{false, false, false, true}},
+		{"empty", [2]int{0, 0}, [2]int{60, 100}, want{true, false, true, true}},
+	}
+	sets := map[string]func(m, n int) set.Set{
+		"getStatic":   func(m, n int) set.Set { return getStatic(200, m, n) },
+		"getDynamic":  func(m, n int) set.Set { return getDynamic(0, m, n) },
+		"getRoaring":  func(m, n int) set.Set { return getRoaring(0, m, n) },
+		"getMutexSet": func(m, n int) set.Set { return getMutexSet(200, m, n) },
+	}
+	for _, c := range cases {
+		for sn, sf := range sets {
+			for tn, tf := range sets {
+				t.Run(c.name+"/"+sn+"+"+tn, func(t *testing.T) {
+					s, x := sf(c.s[0], c.s[1]), tf(c.t[0], c.t[1])
+					got := want{
+						set.IsSubset(s, x),
+						set.IsSuperset(s, x),
+						set.IsProperSubset(s, x),
+						set.Disjoint(s, x),
+					}
+					if got != c.expect {
+						t.Fatalf("got %+v, want %+v", got, c.expect)
+					}
+					if set.Intersects(s, x) == got.disjoint {
+						t.Fatalf("Intersects() == Disjoint()")
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestOperationSize(t *testing.T) {
+	ops := []struct {
+		name string
+		size func(s, t set.Set) int
+		want opFunc
+	}{
+		{"UnionSize", set.UnionSize, set.Union},
+		{"IntersectSize", set.IntersectSize, set.Intersect},
+		{"DifferenceSize", set.DifferenceSize, set.Difference},
+		{"SymmetricDifferenceSize", set.SymmetricDifferenceSize, set.Complement},
+	}
+	sets := map[string]func(m, n int) set.Set{
+		"getStatic":   func(m, n int) set.Set { return getStatic(1000, m, n) },
+		"getDynamic":  func(m, n int) set.Set { return getDynamic(0, m, n) },
+		"getRoaring":  func(m, n int) set.Set { return getRoaring(0, m, n) },
+		"getMutexSet": func(m, n int) set.Set { return getMutexSet(1000, m, n) },
+	}
+	for _, op := range ops {
+		for sn, sf := range sets {
+			for tn, tf := range sets {
+				t.Run(op.name+"/"+sn+"+"+tn, func(t *testing.T) {
+					s, x := sf(0, 500), tf(300, 900)
+					want := len(set.Items(op.want(s, x)))
+					if got := op.size(s, x); got != want {
+						t.Fatalf("got %d, want %d", got, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestOperationSizeAllocs(t *testing.T) {
+	s, x := getStatic(1000, 0, 500), getDynamic(0, 300, 900)
+	allocs := testing.AllocsPerRun(100, func() {
+		set.UnionSize(s, x)
+		set.IntersectSize(x, s)
+		set.DifferenceSize(s, x)
+		set.SymmetricDifferenceSize(x, s)
+	})
+	if allocs != 0 {
+		t.Fatalf("allocs = %v, want 0", allocs)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	sets := map[string]func(m, n int) set.Set{
+		"getStatic":   func(m, n int) set.Set { return getStatic(1000, m, n) },
+		"getDynamic":  func(m, n int) set.Set { return getDynamic(0, m, n) },
+		"getRoaring":  func(m, n int) set.Set { return getRoaring(0, m, n) },
+		"getMutexSet": func(m, n int) set.Set { return getMutexSet(1000, m, n) },
+	}
+	// |S|=400 |T|=100 |S∩T|=50
+	for sn, sf := range sets {
+		for tn, tf := range sets {
+			t.Run(sn+"+"+tn, func(t *testing.T) {
+				s, x := sf(0, 400), tf(350, 450)
+				for _, c := range []struct {
+					name      string
+					got, want float64
+				}{
+					{"Jaccard", set.Jaccard(s, x), 50.0 / 450},
+					{"Dice", set.Dice(s, x), 100.0 / 500},
+					{"Overlap", set.Overlap(s, x), 50.0 / 100},
+					{"Cosine", set.Cosine(s, x), 50.0 / 200},
+					{"Hamming", float64(set.Hamming(s, x)), 400},
+					{"Jaccard empty", set.Jaccard(sf(0, 0), tf(0, 0)), 1},
+					{"Overlap one empty", set.Overlap(s, tf(0, 0)), 0},
+				} {
+					if math.Abs(c.got-c.want) > 1e-9 {
+						t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+					}
+				}
+			})
+		}
+	}
+}
+
+// rangeCounter count the Range calls of the set.
+type rangeCounter struct {
+	set.Set
+	ranges int
+}
+
+func (s *rangeCounter) Range(f func(x uint32) bool) {
+	s.ranges += 1
+	s.Set.Range(f)
+}
+
+func TestSimilarityOnePass(t *testing.T) {
+	s := &rangeCounter{Set: getMutexSet(1000, 0, 400)}
+	x := &rangeCounter{Set: getMutexSet(1000, 350, 450)}
+	if h := set.Hamming(s, x); h != 400 {
+		t.Fatalf("Hamming = %d, want 400", h)
+	}
+	if s.ranges != 1 || x.ranges != 1 {
+		t.Fatalf("Range called %d and %d times, want 1", s.ranges, x.ranges)
+	}
+}
+
+func TestNotFull(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		s    set.Set
+		max  int
+	}{
+		{"Static", getStatic(100, 10, 50), 100},
+		{"Dynamic", getDynamic(1000, 10, 500), 1000},
+		{"Roaring", getRoaring(200000, 10, 70000), 200000},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			n := set.Not(c.s)
+			if reflect.TypeOf(n) != reflect.TypeOf(c.s) {
+				t.Fatalf("Not() type %T, want %T", n, c.s)
+			}
+			if !set.Disjoint(n, c.s) {
+				t.Fatalf("Not() not disjoint")
+			}
+			if got, want := set.Size(n)+set.Size(c.s), c.max+1; got != want {
+				t.Fatalf("Size() = %d, want %d", got, want)
+			}
+			var last uint32
+			n.Range(func(x uint32) bool {
+				last = x
+				return true
+			})
+			if last != uint32(c.max) {
+				t.Fatalf("last item %d, want %d", last, c.max)
+			}
+			if !set.Equal(set.Not(n), c.s) {
+				t.Fatalf("Not(Not(s)) != s")
+			}
+		})
+	}
+	for _, c := range []struct {
+		name string
+		s    set.Set
+		max  int
+	}{
+		{"Full", set.Full(100), 100},
+		{"FullDynamic", set.FullDynamic(1000), 1000},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := set.Size(c.s); got != c.max+1 {
+				t.Fatalf("Size() = %d, want %d", got, c.max+1)
+			}
+			if !set.Null(set.Not(c.s)) {
+				t.Fatalf("Not(Full) not empty")
+			}
+		})
+	}
+	// a Dynamic without max complement in [0,maximum],
+	// equal sets have equal complements whatever they have allocated.
+	var d, grown set.Dynamic
+	d.Store(5)
+	grown.Store(1 << 20)
+	grown.Delete(1 << 20)
+	grown.Store(5)
+	for _, s := range []*set.Dynamic{&d, &grown} {
+		n := s.Not()
+		last, _ := n.Max()
+		if size := set.Size(n); size != int(maximum) || n.Load(5) || last != maximum {
+			t.Fatalf("Dynamic{} Not() = %d items, last %d", size, last)
+		}
+	}
+	b := set.FullBase(10, -10)
+	b.Remove(0)
+	nb := b.Not()
+	var got []int32
+	nb.Range(func(x int32) bool {
+		got = append(got, x)
+		return true
+	})
+	if !reflect.DeepEqual(got, []int32{0}) {
+		t.Fatalf("Base Not() = %v, want [0]", got)
+	}
+}
+
+func TestBulkStore(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var xs []uint32
+	for i := 0; i < 100000; i++ {
+		if r.Intn(4) == 0 {
+			xs = append(xs, uint32(i))
+		}
+	}
+	want := set.NewStatic(100000)
+	set.Adds(want, xs...)
+
+	s := set.FromSorted(xs)
+	if !set.Equal(s, want) || set.Size(s) != len(xs) {
+		t.Fatalf("FromSorted() size %d want %d", set.Size(s), len(xs))
+	}
+	// not sorted, the biggest item is not the last.
+	rev := append([]uint32(nil), xs...)
+	slices.Reverse(rev)
+	if s := set.FromSorted(rev); !set.Equal(s, want) || set.Size(s) != len(xs) {
+		t.Fatalf("FromSorted(reverse) size %d want %d", set.Size(s), len(xs))
+	}
+
+	words := make([]uint64, 100000/64+1)
+	for _, x := range xs {
+		words[x/64] |= 1 << (x % 64)
+	}
+	w := set.FromWords(words, 0)
+	if !set.Equal(w, want) || set.Size(w) != len(xs) {
+		t.Fatalf("FromWords() size %d want %d", set.Size(w), len(xs))
+	}
+	// drop the items bigger than max.
+	w = set.FromWords(words, 1000)
+	if x, _ := w.Max(); x > 1000 || set.Size(w) != want.(*set.Static).CountRange(0, 1000) {
+		t.Fatalf("FromWords(1000) = %v", w)
+	}
+
+	var d set.Dynamic
+	d.OnceInit(0)
+	// unsorted and grow.
+	if !d.StoreMany(xs[len(xs)/2:]) || !d.StoreMany(xs[:len(xs)/2]) {
+		t.Fatalf("StoreMany() overflow")
+	}
+	if !set.Equal(&d, want) || set.Size(&d) != len(xs) {
+		t.Fatalf("Dynamic StoreMany() size %d want %d", set.Size(&d), len(xs))
+	}
+	var st set.Static
+	st.OnceInit(1000)
+	if st.StoreMany([]uint32{1, 2, 1001, 999}) || set.Size(&st) != 3 {
+		t.Fatalf("Static StoreMany() overflow = %v", &st)
+	}
+}
+
+func TestAppendItems(t *testing.T) {
+	xs := []uint32{0, 3, 63, 64, 100, 500, 999}
+	for _, s := range []Interface{
+		set.NewStatic(1000, xs...),
+		set.NewDynamic(1000, xs...),
+		getRoaring(1000, 0, 0),
+		getMutexSet(1000, 0, 0),
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			set.Adds(s, xs...)
+			buf := make([]uint32, 0, 64)
+			buf = set.AppendItems(buf[:0], s)
+			if !reflect.DeepEqual(buf, xs) {
+				t.Fatalf("AppendItems() = %v want %v", buf, xs)
+			}
+			if items := set.Items(s); len(items) != cap(items) {
+				t.Fatalf("Items() len %d cap %d", len(items), cap(items))
+			}
+			words := make([]uint64, 16)
+			if n := set.CopyWords(words, s); n != 999/64+1 {
+				t.Fatalf("CopyWords() = %d want %d", n, 999/64+1)
+			}
+			if got := set.Items(set.FromWords(words, 0)); !reflect.DeepEqual(got, xs) {
+				t.Fatalf("CopyWords() items = %v want %v", got, xs)
+			}
+			short := make([]uint64, 2)
+			if n := set.CopyWords(short, s); n != 2 || short[1] != 1|1<<36 {
+				t.Fatalf("CopyWords() short = %d %x", n, short)
+			}
+		})
+	}
+}
+
+func TestAppendItemsAllocs(t *testing.T) {
+	for _, s := range []set.Set{
+		set.NewStatic(1<<16, 1, 2, 1000, 60000),
+		set.NewDynamic(1<<16, 1, 2, 1000, 60000),
+	} {
+		buf := make([]uint32, 0, 16)
+		words := make([]uint64, 1<<10)
+		n := testing.AllocsPerRun(100, func() {
+			buf = set.AppendItems(buf[:0], s)
+			set.CopyWords(words, s)
+		})
+		if n != 0 {
+			t.Fatalf("%T allocs = %v want 0", s, n)
+		}
+	}
+}
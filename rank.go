@@ -0,0 +1,151 @@
+package set
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// number of words in each block of rankIndex.
+const rankBlock = 64
+
+// rankIndex is a fenwick tree of the item count in each block of words.
+// it's updated by atomic add after the word's CAS success,
+// so concurrent Rank may miss the items being stored or deleted,
+// but it's exact once the writers finish.
+type rankIndex struct {
+	// tree[0] unused, tree[b] hold the count of blocks (b-b&-b,b].
+	tree []uint32
+}
+
+// newRankIndex build a rankIndex from the words of s.
+func newRankIndex(s bitSet) *rankIndex {
+	n := (int(s.getCap()) + rankBlock - 1) / rankBlock
+	r := &rankIndex{tree: make([]uint32, n+1)}
+	slen := int(s.getLen())
+	for i := 0; i < slen; i++ {
+		r.tree[i/rankBlock+1] += uint32(bits.OnesCount32(s.load(i)))
+	}
+	// build the tree in O(n)
+	for b := 1; b <= n; b++ {
+		if p := b + b&-b; p <= n {
+			r.tree[p] += r.tree[b]
+		}
+	}
+	return r
+}
+
+// add delta to the block of word i.
+func (r *rankIndex) add(i int, delta int) {
+	for b := i/rankBlock + 1; b < len(r.tree); b += b & -b {
+		atomic.AddUint32(&r.tree[b], uint32(delta))
+	}
+}
+
+// prefix return the number of items in blocks [0,block).
+func (r *rankIndex) prefix(block int) int {
+	var sum uint32
+	for b := block; b > 0; b -= b & -b {
+		sum += atomic.LoadUint32(&r.tree[b])
+	}
+	return int(int32(sum))
+}
+
+// search return the block of the k-th item,
+// and the number of items before it in the block.
+func (r *rankIndex) search(k int) (block, rest int) {
+	step := 1
+	for step*2 < len(r.tree) {
+		step *= 2
+	}
+	for ; step > 0; step >>= 1 {
+		if block+step < len(r.tree) {
+			v := int(int32(atomic.LoadUint32(&r.tree[block+step])))
+			if v <= k {
+				block += step
+				k -= v
+			}
+		}
+	}
+	return block, k
+}
+
+// rankBit return the number of items in s which <= x.
+// r is the rank index of s, scan from 0 if nil.
+func rankBit(s bitSet, r *rankIndex, x uint32) int {
+	w := s.wordBits()
+	slen := int(s.getLen())
+	idx, mod := int(x/w), x%w
+	end := min(idx, slen)
+	sum, i := 0, 0
+	if r != nil {
+		sum = r.prefix(end / rankBlock)
+		i = end / rankBlock * rankBlock
+	}
+	for ; i < end; i++ {
+		sum += bits.OnesCount32(s.load(i))
+	}
+	if idx < slen {
+		sum += bits.OnesCount32(s.load(idx) & (2<<mod - 1))
+	}
+	return sum
+}
+
+// selectBit return the k-th smallest item in s, k start from 0.
+// r is the rank index of s, scan from 0 if nil.
+func selectBit(s bitSet, r *rankIndex, k int) (uint32, bool) {
+	if k < 0 {
+		return 0, false
+	}
+	w := s.wordBits()
+	slen := int(s.getLen())
+	i := 0
+	if r != nil {
+		var block int
+		block, k = r.search(k)
+		i = block * rankBlock
+	}
+	for ; i < slen; i++ {
+		item := s.load(i)
+		n := bits.OnesCount32(item)
+		if k < n {
+			return uint32(i)*w + selectInWord(item, k), true
+		}
+		k -= n
+	}
+	return 0, false
+}
+
+// countBit return the number of items in s which in [lo,hi].
+func countBit(s bitSet, lo, hi uint32) int {
+	if lo > hi {
+		return 0
+	}
+	w := s.wordBits()
+	slen := int(s.getLen())
+	loIdx, loMod := int(lo/w), lo%w
+	hiIdx, hiMod := int(hi/w), hi%w
+	if loIdx >= slen {
+		return 0
+	}
+	if loIdx == hiIdx {
+		item := s.load(loIdx) & (2<<hiMod - 1)
+		return bits.OnesCount32(item >> loMod)
+	}
+	sum := bits.OnesCount32(s.load(loIdx) >> loMod)
+	end := min(hiIdx, slen)
+	for i := loIdx + 1; i < end; i++ {
+		sum += bits.OnesCount32(s.load(i))
+	}
+	if hiIdx < slen {
+		sum += bits.OnesCount32(s.load(hiIdx) & (2<<hiMod - 1))
+	}
+	return sum
+}
+
+// selectInWord return the position of the k-th set bit in item.
+func selectInWord(item uint32, k int) uint32 {
+	for ; k > 0; k-- {
+		item &= item - 1
+	}
+	return uint32(bits.TrailingZeros32(item))
+}
@@ -0,0 +1,339 @@
+package set
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Roaring a compressed set of non-negative integers.
+// Its zero value represents the empty set.
+//
+// x is an item in set.
+// x = hb<<16 | lb, the high 16 bits hb is the key of a chunk,
+// the low 16 bits lb store in the chunk's container:
+// array container for sparse chunk,
+// bitmap container for dense chunk,
+// run container for clustered chunk.
+//
+// Roaring use a read write lock,Load can run in parallel
+// but Store and Delete are serialized.
+// general use for sparse item spread across the whole uint32 range.
+type Roaring struct {
+	once sync.Once
+	mu   sync.RWMutex
+
+	// max input x
+	max uint32
+
+	// sorted chunk keys
+	keys []uint16
+
+	// containers of keys
+	conts []container
+}
+
+func (s *Roaring) onceInit(max int) {
+	s.once.Do(func() {
+		if max < 1 || int64(max) > math.MaxUint32 {
+			max = math.MaxUint32
+		}
+		atomic.StoreUint32(&s.max, uint32(max))
+	})
+}
+
+// OnceInit initialize set use max
+// it only execute once time.
+// if max<1, will use the whole uint32 range.
+func (s *Roaring) OnceInit(max int) { s.onceInit(max) }
+
+func (s *Roaring) getMax() uint32 {
+	s.onceInit(0)
+	return atomic.LoadUint32(&s.max)
+}
+
+// find return the index of key hb, and whether it exist.
+// must hold mu.
+func (s *Roaring) find(hb uint16) (int, bool) {
+	i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] >= hb })
+	return i, i < len(s.keys) && s.keys[i] == hb
+}
+
+// Load reports whether the set contains the non-negative value x.
+// time complexity: O(log N)
+func (s *Roaring) Load(x uint32) bool {
+	if x > s.getMax() {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, ok := s.find(uint16(x >> 16))
+	return ok && s.conts[i].contains(uint16(x))
+}
+
+// Store adds the non-negative value x to the set.
+// return false if x overflow bigger than max.
+func (s *Roaring) Store(x uint32) bool {
+	_, ok := s.LoadOrStore(x)
+	return ok
+}
+
+// LoadOrStore adds the non-negative value x to the set.
+// loaded report x if in set,ok report x if overflow
+func (s *Roaring) LoadOrStore(x uint32) (loaded, ok bool) {
+	if x > s.getMax() {
+		return false, false
+	}
+	hb := uint16(x >> 16)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, exist := s.find(hb)
+	if !exist {
+		s.keys = append(s.keys, 0)
+		copy(s.keys[i+1:], s.keys[i:])
+		s.keys[i] = hb
+		s.conts = append(s.conts, nil)
+		copy(s.conts[i+1:], s.conts[i:])
+		s.conts[i] = newArrayContainer(1)
+	}
+	c, added := s.conts[i].add(uint16(x))
+	s.conts[i] = c
+	return !added, true
+}
+
+// Delete remove x from the set
+// return true if success, false if x overflow
+func (s *Roaring) Delete(x uint32) bool {
+	_, ok := s.LoadAndDelete(x)
+	return ok
+}
+
+// LoadAndDelete remove x from the set
+// loaded report x if in set,ok report x if overflow
+func (s *Roaring) LoadAndDelete(x uint32) (loaded, ok bool) {
+	if x > s.getMax() {
+		return false, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, exist := s.find(uint16(x >> 16))
+	if !exist {
+		return false, true
+	}
+	c, removed := s.conts[i].remove(uint16(x))
+	if c.card() == 0 {
+		s.keys = append(s.keys[:i], s.keys[i+1:]...)
+		s.conts = append(s.conts[:i], s.conts[i+1:]...)
+	} else {
+		s.conts[i] = c
+	}
+	return removed, true
+}
+
+// Range calls f sequentially for each item present in the set.
+// If f returns false, range stops the iteration.
+//
+// Range does not necessarily correspond to any consistent snapshot of the set's
+// contents: each chunk is read under lock and f is called without it,
+// so f may Store or Delete items of the set.
+func (s *Roaring) Range(f func(x uint32) bool) {
+	var buf []uint32
+	next := 0
+	for next <= math.MaxUint16 {
+		s.mu.RLock()
+		i, _ := s.find(uint16(next))
+		if i >= len(s.keys) {
+			s.mu.RUnlock()
+			return
+		}
+		hb := s.keys[i]
+		buf = buf[:0]
+		s.conts[i].iterate(uint32(hb)<<16, func(x uint32) bool {
+			buf = append(buf, x)
+			return true
+		})
+		s.mu.RUnlock()
+		for _, x := range buf {
+			if !f(x) {
+				return
+			}
+		}
+		next = int(hb) + 1
+	}
+}
+
+// RunOptimize convert each container to the smallest one
+// of array, bitmap and run container.
+func (s *Roaring) RunOptimize() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.conts {
+		s.conts[i] = optimize(c)
+	}
+}
+
+// Not return a new set of the items in [0,max] but not in s.
+func (s *Roaring) Not() *Roaring {
+	var p Roaring
+	max := s.getMax()
+	p.OnceInit(int(max))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := 0
+	for hb := 0; hb <= int(max>>16); hb++ {
+		// the last chunk only hold items <= max
+		last := uint16(math.MaxUint16)
+		if hb == int(max>>16) {
+			last = uint16(max)
+		}
+		if i < len(s.keys) && int(s.keys[i]) == hb {
+			var w [bitmapWords]uint64
+			setRange64(w[:], 0, uint32(last))
+			cw := s.conts[i].words()
+			for j := range w {
+				w[j] &^= cw[j]
+			}
+			p.appendContainer(uint16(hb), fromWords(&w))
+			i += 1
+			continue
+		}
+		p.appendContainer(uint16(hb), &runContainer{runs: []interval16{{0, last}}})
+	}
+	return &p
+}
+
+// String returns the set as a string of the form "{1 2 3}".
+func (s *Roaring) String() string { return String(s) }
+
+// size return the number of items in s.
+func (s *Roaring) size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	for _, c := range s.conts {
+		n += c.card()
+	}
+	return n
+}
+
+// clear remove all items from s.
+func (s *Roaring) clear() {
+	s.mu.Lock()
+	s.keys = nil
+	s.conts = nil
+	s.mu.Unlock()
+}
+
+// rLockBoth read lock s and t in address order, prevent dead lock.
+func rLockBoth(s, t *Roaring) (unlock func()) {
+	if s == t {
+		s.mu.RLock()
+		return s.mu.RUnlock
+	}
+	if uintptr(unsafe.Pointer(s)) > uintptr(unsafe.Pointer(t)) {
+		s, t = t, s
+	}
+	s.mu.RLock()
+	t.mu.RLock()
+	return func() {
+		t.mu.RUnlock()
+		s.mu.RUnlock()
+	}
+}
+
+// appendContainer add container c with key hb to the end of s,
+// c is ignore if nil. must hold s.mu.
+func (s *Roaring) appendContainer(hb uint16, c container) {
+	if c == nil {
+		return
+	}
+	s.keys = append(s.keys, hb)
+	s.conts = append(s.conts, c)
+}
+
+// roaringOperation return set p of the flag operation of s and t.
+// merge the keys of s and t, operate the containers with same key.
+func roaringOperation(s, t *Roaring, flag opFlag) *Roaring {
+	var p Roaring
+	p.OnceInit(opGetMax(s.getMax(), t.getMax(), flag))
+
+	unlock := rLockBoth(s, t)
+	defer unlock()
+	i, j := 0, 0
+	for i < len(s.keys) && j < len(t.keys) {
+		sk, tk := s.keys[i], t.keys[j]
+		switch {
+		case sk == tk:
+			var c container
+			switch flag {
+			case opUnion:
+				c = containerOr(s.conts[i], t.conts[j])
+			case opIntersect:
+				c = containerAnd(s.conts[i], t.conts[j])
+			case opDifference:
+				c = containerAndNot(s.conts[i], t.conts[j])
+			case opComplement:
+				c = containerXor(s.conts[i], t.conts[j])
+			}
+			p.appendContainer(sk, c)
+			i += 1
+			j += 1
+		case sk < tk:
+			if flag != opIntersect {
+				p.appendContainer(sk, s.conts[i].clone())
+			}
+			i += 1
+		default:
+			if flag == opUnion || flag == opComplement {
+				p.appendContainer(tk, t.conts[j].clone())
+			}
+			j += 1
+		}
+	}
+	if flag != opIntersect {
+		for ; i < len(s.keys); i++ {
+			p.appendContainer(s.keys[i], s.conts[i].clone())
+		}
+	}
+	if flag == opUnion || flag == opComplement {
+		for ; j < len(t.keys); j++ {
+			p.appendContainer(t.keys[j], t.conts[j].clone())
+		}
+	}
+	return &p
+}
+
+// roaringEqual report whether s and t hold the same items.
+func roaringEqual(s, t *Roaring) bool {
+	unlock := rLockBoth(s, t)
+	defer unlock()
+	if len(s.keys) != len(t.keys) {
+		return false
+	}
+	for i := range s.keys {
+		if s.keys[i] != t.keys[i] {
+			return false
+		}
+		if !containerEqual(s.conts[i], t.conts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// roaringCopy return a copy of s.
+func roaringCopy(s *Roaring) *Roaring {
+	var p Roaring
+	p.OnceInit(int(s.getMax()))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p.keys = make([]uint16, len(s.keys))
+	p.conts = make([]container, len(s.conts))
+	copy(p.keys, s.keys)
+	for i, c := range s.conts {
+		p.conts[i] = c.clone()
+	}
+	return &p
+}
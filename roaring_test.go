@@ -0,0 +1,117 @@
+package set_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+func TestRoaringContainer(t *testing.T) {
+	var s set.Roaring
+	// chunk 0 grow from array to bitmap,
+	// chunk 3 keep array.
+	for i := 0; i < 10000; i++ {
+		s.Store(uint32(i))
+	}
+	s.Store(3<<16 | 7)
+	s.Store(^uint32(0))
+	if got := set.Size(&s); got != 10002 {
+		t.Fatalf("Size() = %d, want %d", got, 10002)
+	}
+	// bitmap back to array.
+	for i := 100; i < 10000; i++ {
+		if !s.Delete(uint32(i)) {
+			t.Fatalf("delete err:%d", i)
+		}
+	}
+	if got := set.Size(&s); got != 102 {
+		t.Fatalf("Size() = %d, want %d", got, 102)
+	}
+	for i := 0; i < 100; i++ {
+		if !s.Load(uint32(i)) {
+			t.Fatalf("load exist err:%d", i)
+		}
+	}
+	if s.Load(100) || !s.Load(3<<16|7) || !s.Load(^uint32(0)) {
+		t.Fatalf("load err: %v", &s)
+	}
+}
+
+func TestRoaringRunOptimize(t *testing.T) {
+	var s set.Roaring
+	for i := 1000; i < 50000; i++ {
+		s.Store(uint32(i))
+	}
+	want := set.Items(&s)
+	s.RunOptimize()
+	if got := set.Items(&s); !reflect.DeepEqual(got, want) {
+		t.Fatalf("RunOptimize changed items")
+	}
+	// split and join runs.
+	s.Delete(2000)
+	s.Delete(1000)
+	s.Delete(49999)
+	if s.Load(2000) || s.Load(1000) || s.Load(49999) || !s.Load(2001) || !s.Load(1999) {
+		t.Fatalf("run delete err")
+	}
+	s.Store(2000)
+	s.Store(999)
+	if !s.Load(2000) || !s.Load(999) || s.Load(1000) {
+		t.Fatalf("run store err")
+	}
+	if got := set.Size(&s); got != 50000-1000-1 {
+		t.Fatalf("Size() = %d, want %d", got, 50000-1000-1)
+	}
+}
+
+func TestRoaringOperation(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const max = 1 << 20
+	var x, y set.Roaring
+	var sx, sy set.Static
+	sx.OnceInit(max)
+	sy.OnceInit(max)
+	for i := 0; i < 100000; i++ {
+		a, b := uint32(r.Intn(max)), uint32(r.Intn(max/4))
+		x.Store(a)
+		sx.Store(a)
+		y.Store(b)
+		sy.Store(b)
+	}
+	// dense chunk
+	for i := 1 << 17; i < 3<<16; i++ {
+		x.Store(uint32(i))
+		sx.Store(uint32(i))
+	}
+	y.RunOptimize()
+	for _, op := range []struct {
+		name string
+		f    opFunc
+	}{
+		{"Union", set.Union},
+		{"Intersect", set.Intersect},
+		{"Difference", set.Difference},
+		{"Complement", set.Complement},
+	} {
+		t.Run(op.name, func(t *testing.T) {
+			got := op.f(&x, &y)
+			if _, ok := got.(*set.Roaring); !ok {
+				t.Fatalf("want *set.Roaring, got %T", got)
+			}
+			want := op.f(&sx, &sy)
+			if !reflect.DeepEqual(set.Items(got), set.Items(want)) {
+				t.Fatalf("%s not equal", op.name)
+			}
+		})
+	}
+	c := set.Copy(&x)
+	if !set.Equal(c, &x) || set.Equal(c, &y) {
+		t.Fatalf("Copy not equal")
+	}
+	set.Clear(c)
+	if !set.Null(c) || set.Null(&x) {
+		t.Fatalf("Clear err")
+	}
+}
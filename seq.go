@@ -0,0 +1,100 @@
+package set
+
+import "iter"
+
+// All return an iterator over the items of the set in ascending order.
+//
+//	for x := range s.All() { ... }
+//
+// the same as Range, it does not correspond to any consistent snapshot.
+func (s *Static) All() iter.Seq[uint32] { return s.Range }
+
+// Backward return an iterator over the items of the set in descending order.
+func (s *Static) Backward() iter.Seq[uint32] { return s.RangeReverse }
+
+// Between return an iterator over the items in [lo,hi] in ascending order.
+func (s *Static) Between(lo, hi uint32) iter.Seq[uint32] {
+	return func(yield func(uint32) bool) { s.RangeBetween(lo, hi, yield) }
+}
+
+// All return an iterator over the items of the set in ascending order.
+//
+//	for x := range s.All() { ... }
+//
+// the same as Range, it does not correspond to any consistent snapshot.
+func (s *Dynamic) All() iter.Seq[uint32] { return s.Range }
+
+// Backward return an iterator over the items of the set in descending order.
+func (s *Dynamic) Backward() iter.Seq[uint32] { return s.RangeReverse }
+
+// Between return an iterator over the items in [lo,hi] in ascending order.
+func (s *Dynamic) Between(lo, hi uint32) iter.Seq[uint32] {
+	return func(yield func(uint32) bool) { s.RangeBetween(lo, hi, yield) }
+}
+
+// All return an iterator over the items of the set in ascending order.
+func (s *Base) All() iter.Seq[int32] { return s.Range }
+
+// Backward return an iterator over the items of the set in descending order.
+func (s *Base) Backward() iter.Seq[int32] { return s.RangeReverse }
+
+// Between return an iterator over the items in [lo,hi] in ascending order.
+func (s *Base) Between(lo, hi int32) iter.Seq[int32] {
+	return func(yield func(int32) bool) { s.RangeBetween(lo, hi, yield) }
+}
+
+// All return an iterator over the items of the set.
+// Map is not ordered, so it has no Backward and Between.
+func (s *Map) All() iter.Seq[interface{}] { return s.Range }
+
+// Collect return a Dynamic set with the items of seq.
+func Collect(seq iter.Seq[uint32]) Set {
+	return CollectDynamic(0, seq)
+}
+
+// CollectStatic return a Static set with the items of seq.
+// cap is set cap,if cap<1,will use 256.
+// the items bigger than cap are dropped.
+func CollectStatic(cap int, seq iter.Seq[uint32]) Set {
+	var s Static
+	s.OnceInit(cap)
+	collect(&s, seq)
+	return &s
+}
+
+// CollectDynamic return a Dynamic set with the items of seq.
+// cap is set cap,if cap<1,the set grow to hold the items.
+func CollectDynamic(cap int, seq iter.Seq[uint32]) Set {
+	var s Dynamic
+	s.OnceInit(cap)
+	collect(&s, seq)
+	return &s
+}
+
+// CollectBase return a Base set in [min,max] with the items of seq.
+// the items out of [min,max] are dropped.
+func CollectBase(max, min int, seq iter.Seq[int32]) *Base {
+	s := NewBase(max, min)
+	for x := range seq {
+		s.Add(x)
+	}
+	return s
+}
+
+// CollectMap return a Map with the items of seq.
+func CollectMap(seq iter.Seq[interface{}]) *Map {
+	var s Map
+	for x := range seq {
+		s.Add(x)
+	}
+	return &s
+}
+
+func collect(s Set, seq iter.Seq[uint32]) {
+	for x := range seq {
+		s.Store(x)
+	}
+}
+
+// All return an iterator over the items of the set in ascending order.
+func (s *Persistent) All() iter.Seq[uint32] { return s.Range }
@@ -0,0 +1,92 @@
+package set_test
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+type seqSet interface {
+	Interface
+	All() iter.Seq[uint32]
+	Backward() iter.Seq[uint32]
+	Between(lo, hi uint32) iter.Seq[uint32]
+}
+
+func TestSeq(t *testing.T) {
+	items := []uint32{0, 3, 15, 16, 31, 32, 64, 500, 999, 1000}
+	for _, s := range [...]seqSet{
+		&set.Static{},
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			s.OnceInit(1000)
+			set.Adds(s, items...)
+			if got := slices.Collect(s.All()); !reflect.DeepEqual(got, items) {
+				t.Fatalf("All() = %v want %v", got, items)
+			}
+			want := slices.Clone(items)
+			slices.Reverse(want)
+			if got := slices.Collect(s.Backward()); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Backward() = %v want %v", got, want)
+			}
+			if got := slices.Collect(s.Between(16, 64)); !reflect.DeepEqual(got, []uint32{16, 31, 32, 64}) {
+				t.Fatalf("Between(16,64) = %v", got)
+			}
+			// break stop the iteration.
+			n := 0
+			for range s.All() {
+				n++
+				if n == 3 {
+					break
+				}
+			}
+			if n != 3 {
+				t.Fatalf("break at %d", n)
+			}
+			if c := set.Collect(s.All()); !set.Equal(c, s) {
+				t.Fatalf("Collect() = %v want %v", c, s)
+			}
+			if c := set.CollectStatic(1000, s.All()); !set.Equal(c, s) {
+				t.Fatalf("CollectStatic() = %v want %v", c, s)
+			}
+		})
+	}
+}
+
+func TestBaseSeq(t *testing.T) {
+	b := set.NewBase(100, -100)
+	for _, x := range []int32{-100, -3, 40, 100} {
+		b.Add(x)
+	}
+	if got := slices.Collect(b.Backward()); !reflect.DeepEqual(got, []int32{100, 40, -3, -100}) {
+		t.Fatalf("Backward() = %v", got)
+	}
+	if got := slices.Collect(b.Between(-50, 50)); !reflect.DeepEqual(got, []int32{-3, 40}) {
+		t.Fatalf("Between(-50,50) = %v", got)
+	}
+	c := set.CollectBase(100, -100, b.All())
+	if got := slices.Collect(c.All()); !reflect.DeepEqual(got, []int32{-100, -3, 40, 100}) {
+		t.Fatalf("CollectBase() = %v", got)
+	}
+}
+
+func TestMapSeq(t *testing.T) {
+	var m set.Map
+	for _, x := range []string{"a", "b", "c"} {
+		m.Add(x)
+	}
+	c := set.CollectMap(m.All())
+	var got []string
+	for x := range c.All() {
+		got = append(got, x.(string))
+	}
+	slices.Sort(got)
+	if !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("CollectMap() = %v", got)
+	}
+}
@@ -22,6 +22,7 @@ func benchMap(b *testing.B, bench bench) {
 	for _, m := range [...]Interface{
 		&set.Dynamic{},
 		&set.Static{},
+		&set.Roaring{},
 	} {
 		b.Run(fmt.Sprintf("%T", m), func(b *testing.B) {
 			m = reflect.New(reflect.TypeOf(m).Elem()).Interface().(Interface)
@@ -271,6 +272,15 @@ func getDynamic(cap, m, n int) *set.Dynamic {
 	return &s
 }
 
+func getRoaring(cap, m, n int) *set.Roaring {
+	var s set.Roaring
+	s.OnceInit(cap)
+	for i := m; i < n; i++ {
+		s.Store(uint32(i))
+	}
+	return &s
+}
+
 func getMutexSet(cap, m, n int) *MutexSet {
 	var s MutexSet
 	s.OnceInit(cap)
@@ -297,6 +307,9 @@ func call(b *testing.B, op opType, invert bool) {
 
 		{"TT", getDynamic(cap1, start1, end1), getDynamic(cap2, start2, end2)},
 
+		{"RR", getRoaring(cap1, start1, end1), getRoaring(cap2, start2, end2)},
+		{"RS", getRoaring(cap1, start1, end1), getStatic(cap2, start2, end2)},
+
 		{"MM", getMutexSet(cap1, start1, end1), getMutexSet(cap2, start2, end2)},
 		{"MS", getMutexSet(cap1, start1, end1), getStatic(cap2, start2, end2)},
 		{"MT", getMutexSet(cap1, start1, end1), getDynamic(cap2, start2, end2)},
@@ -327,3 +340,150 @@ func BenchmarkDifference(b *testing.B) {
 func BenchmarkComplement(b *testing.B) {
 	call(b, opComplement, false)
 }
+
+func BenchmarkIntersectAll(b *testing.B) {
+	sets := make([]set.Set, 30)
+	for i := range sets {
+		sets[i] = getStatic(1<<16, i, 1<<16)
+	}
+	b.Run("IntersectAll", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			set.IntersectAll(sets...)
+		}
+	})
+	b.Run("Intersect", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p := sets[0]
+			for _, s := range sets[1:] {
+				p = set.Intersect(p, s)
+			}
+		}
+	})
+}
+
+type wordsSet interface {
+	Interface
+	RangeWords(f func(base uint32, word uint64) bool)
+}
+
+func BenchmarkRangeDense(b *testing.B) {
+	const mapSize = 1 << 16
+	half := func(s Interface) Interface {
+		for i := 0; i < mapSize; i += 2 {
+			s.Delete(uint32(i))
+		}
+		return s
+	}
+	for _, m := range [...]struct {
+		name string
+		s    wordsSet
+	}{
+		{"Dynamic", getDynamic(mapSize, 0, mapSize)},
+		{"Static", getStatic(mapSize, 0, mapSize)},
+		{"DynamicHalf", half(getDynamic(mapSize, 0, mapSize)).(wordsSet)},
+		{"StaticHalf", half(getStatic(mapSize, 0, mapSize)).(wordsSet)},
+	} {
+		b.Run(m.name+"/Range", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m.s.Range(func(x uint32) bool { return true })
+			}
+		})
+		b.Run(m.name+"/RangeWords", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m.s.RangeWords(func(base uint32, word uint64) bool { return true })
+			}
+		})
+	}
+}
+
+func BenchmarkStoreMany(b *testing.B) {
+	const mapSize = 1 << 20
+	xs := make([]uint32, mapSize)
+	for i := range xs {
+		xs[i] = uint32(i)
+	}
+	b.Run("Adds", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var s set.Static
+			s.OnceInit(mapSize)
+			set.Adds(&s, xs...)
+		}
+	})
+	b.Run("FromSorted", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			set.FromSorted(xs)
+		}
+	})
+}
+
+// BenchmarkStaticWrite the cost of the Snapshot writer gate,
+// Gated is a set once snapshot.
+func BenchmarkStaticWrite(b *testing.B) {
+	const mapSize = 1 << 20
+	write := func(b *testing.B, s *set.Static) {
+		for i := 0; i < b.N; i++ {
+			x := uint32(i) % mapSize
+			s.Store(x)
+			s.Delete(x)
+		}
+	}
+	b.Run("NoSnapshot", func(b *testing.B) {
+		var s set.Static
+		s.OnceInit(mapSize)
+		write(b, &s)
+	})
+	b.Run("Gated", func(b *testing.B) {
+		var s set.Static
+		s.OnceInit(mapSize)
+		s.Snapshot()
+		write(b, &s)
+	})
+}
+
+func BenchmarkClone(b *testing.B) {
+	const mapSize = 1 << 24
+	var s set.Static
+	s.OnceInit(mapSize)
+	s.AddRange(0, mapSize)
+	b.Run("Copy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p := set.Copy(&s)
+			p.Store(uint32(i) % mapSize)
+		}
+	})
+	b.Run("Clone", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p := s.Clone()
+			p.Store(uint32(i) % mapSize)
+		}
+	})
+	// the first write of the source after Clone.
+	b.Run("Source", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.Clone()
+			s.Delete(uint32(i) % mapSize)
+			s.Store(uint32(i) % mapSize)
+		}
+	})
+}
+
+// BenchmarkStaticLoad Load of a never cloned set and a clone.
+func BenchmarkStaticLoad(b *testing.B) {
+	const mapSize = 1 << 20
+	var s set.Static
+	s.OnceInit(mapSize)
+	s.AddRange(0, mapSize/2)
+	load := func(b *testing.B, s *set.Static) {
+		n := 0
+		for i := 0; i < b.N; i++ {
+			if s.Load(uint32(i) % mapSize) {
+				n++
+			}
+		}
+		if n > b.N {
+			b.Fatalf("Load() hits %d", n)
+		}
+	}
+	b.Run("Uncloned", func(b *testing.B) { load(b, &s) })
+	b.Run("Cloned", func(b *testing.B) { load(b, s.Clone()) })
+}
@@ -116,3 +116,22 @@ func ExampleComplement() {
 	// {0 1 2 3 6 7 8}
 	// {0 1 2 3 6 7 8}
 }
+
+func ExampleStatic_All() {
+	var s set.Static
+	s.OnceInit(100)
+	set.Adds(&s, 3, 1, 64, 99)
+	for x := range s.All() {
+		fmt.Print(x, ",")
+	}
+	fmt.Println()
+	for x := range s.Backward() {
+		fmt.Print(x, ",")
+	}
+	fmt.Println()
+	fmt.Println(set.CollectStatic(100, s.Between(2, 64)))
+	// Output:
+	// 1,3,64,99,
+	// 99,64,3,1,
+	// {3 64}
+}
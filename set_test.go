@@ -2,6 +2,7 @@ package set_test
 
 import (
 	"fmt"
+	"math/bits"
 	"math/rand"
 	"reflect"
 	"runtime"
@@ -88,6 +89,10 @@ func applyTrends(calls []setCall) ([]setResult, map[interface{}]interface{}) {
 	return applyCalls(new(set.Static), calls)
 }
 
+func applyRoaring(calls []setCall) ([]setResult, map[interface{}]interface{}) {
+	return applyCalls(new(set.Roaring), calls)
+}
+
 func applyMutex(calls []setCall) ([]setResult, map[interface{}]interface{}) {
 	return applyCalls(new(MutexSet), calls)
 }
@@ -95,6 +100,7 @@ func applyMutex(calls []setCall) ([]setResult, map[interface{}]interface{}) {
 func applyMap(t *testing.T, standard applyFunc) {
 	for _, m := range [...]applyFunc{
 		applyStatic,
+		applyRoaring,
 		// applyTrends,
 		// applyOpt15,
 		// applyOpt16,
@@ -130,6 +136,10 @@ func TestTrends(t *testing.T) {
 	applyMap(t, applyTrends)
 }
 
+func TestRoaring(t *testing.T) {
+	applyMap(t, applyRoaring)
+}
+
 func TestTrendsGrow(t *testing.T) {
 	src := getDynamic(0, 0, 5000)
 	dst := getDynamic(5000, 0, 5000)
@@ -153,6 +163,7 @@ func queueMap(t *testing.T, test setStruct) {
 	for _, m := range [...]Interface{
 		&set.Static{},
 		&set.Dynamic{},
+		&set.Roaring{},
 	} {
 		t.Run(fmt.Sprintf("%T", m), func(t *testing.T) {
 			m = reflect.New(reflect.TypeOf(m).Elem()).Interface().(Interface)
@@ -432,3 +443,562 @@ func TestConcurrentRace(t *testing.T) {
 		},
 	})
 }
+
+type orderSet interface {
+	Interface
+	Min() (uint32, bool)
+	Max() (uint32, bool)
+	Next(x uint32) (uint32, bool)
+	Prev(x uint32) (uint32, bool)
+}
+
+func TestNextPrev(t *testing.T) {
+	const max = 1000
+	for _, s := range [...]orderSet{
+		&set.Static{},
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			s.OnceInit(max)
+			if _, ok := s.Min(); ok {
+				t.Fatalf("Min() of empty set ok")
+			}
+			if _, ok := s.Max(); ok {
+				t.Fatalf("Max() of empty set ok")
+			}
+			items := []uint32{3, 31, 32, 64, 500, 999}
+			set.Adds(s, items...)
+			if x, ok := s.Min(); !ok || x != 3 {
+				t.Fatalf("Min() = %d,%v want 3", x, ok)
+			}
+			if x, ok := s.Max(); !ok || x != 999 {
+				t.Fatalf("Max() = %d,%v want 999", x, ok)
+			}
+			for x := uint32(0); x <= max+1; x++ {
+				var next, prev uint32
+				var nok, pok bool
+				for _, v := range items {
+					if v >= x && !nok {
+						next, nok = v, true
+					}
+					if v <= x {
+						prev, pok = v, true
+					}
+				}
+				if got, ok := s.Next(x); ok != nok || got != next {
+					t.Fatalf("Next(%d) = %d,%v want %d,%v", x, got, ok, next, nok)
+				}
+				if got, ok := s.Prev(x); ok != pok || got != prev {
+					t.Fatalf("Prev(%d) = %d,%v want %d,%v", x, got, ok, prev, pok)
+				}
+			}
+		})
+	}
+}
+
+func TestBaseNextPrev(t *testing.T) {
+	b := set.NewBase(100, -100)
+	for _, x := range []int32{-100, -3, 40, 100} {
+		b.Add(x)
+	}
+	if x, ok := b.Min(); !ok || x != -100 {
+		t.Fatalf("Min() = %d,%v want -100", x, ok)
+	}
+	if x, ok := b.Max(); !ok || x != 100 {
+		t.Fatalf("Max() = %d,%v want 100", x, ok)
+	}
+	if x, ok := b.Next(-99); !ok || x != -3 {
+		t.Fatalf("Next(-99) = %d,%v want -3", x, ok)
+	}
+	if x, ok := b.Prev(39); !ok || x != -3 {
+		t.Fatalf("Prev(39) = %d,%v want -3", x, ok)
+	}
+	if _, ok := b.Prev(-101); ok {
+		t.Fatalf("Prev(-101) ok")
+	}
+	if _, ok := b.Next(101); ok {
+		t.Fatalf("Next(101) ok")
+	}
+}
+
+type betweenSet interface {
+	Interface
+	RangeFrom(lo uint32, f func(x uint32) bool)
+	RangeBetween(lo, hi uint32, f func(x uint32) bool)
+	RangeReverse(f func(x uint32) bool)
+	RangeReverseBetween(lo, hi uint32, f func(x uint32) bool)
+}
+
+func TestRangeBetween(t *testing.T) {
+	const max = 1000
+	items := []uint32{0, 3, 15, 16, 31, 32, 64, 500, 999, 1000}
+	for _, s := range [...]betweenSet{
+		&set.Static{},
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			s.OnceInit(max)
+			set.Adds(s, items...)
+			var got []uint32
+			collect := func(x uint32) bool {
+				got = append(got, x)
+				return true
+			}
+			for _, c := range []struct{ lo, hi uint32 }{
+				{0, max}, {1, 31}, {16, 16}, {33, 63}, {32, 500}, {600, 1 << 20}, {10, 5},
+			} {
+				var want []uint32
+				for _, x := range items {
+					if x >= c.lo && x <= c.hi {
+						want = append(want, x)
+					}
+				}
+				got = nil
+				s.RangeBetween(c.lo, c.hi, collect)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("RangeBetween(%d,%d) = %v want %v", c.lo, c.hi, got, want)
+				}
+				for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+					want[i], want[j] = want[j], want[i]
+				}
+				got = nil
+				s.RangeReverseBetween(c.lo, c.hi, collect)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("RangeReverseBetween(%d,%d) = %v want %v", c.lo, c.hi, got, want)
+				}
+			}
+			got = nil
+			s.RangeFrom(500, collect)
+			if !reflect.DeepEqual(got, []uint32{500, 999, 1000}) {
+				t.Fatalf("RangeFrom(500) = %v", got)
+			}
+			got = nil
+			s.RangeReverse(collect)
+			if len(got) != len(items) || got[0] != 1000 || got[len(got)-1] != 0 {
+				t.Fatalf("RangeReverse() = %v", got)
+			}
+			// stop early
+			got = nil
+			s.RangeReverse(func(x uint32) bool {
+				got = append(got, x)
+				return len(got) < 2
+			})
+			if !reflect.DeepEqual(got, []uint32{1000, 999}) {
+				t.Fatalf("RangeReverse() stop = %v", got)
+			}
+		})
+	}
+}
+
+func TestBaseRangeBetween(t *testing.T) {
+	b := set.NewBase(100, -100)
+	for _, x := range []int32{-100, -3, 40, 100} {
+		b.Add(x)
+	}
+	var got []int32
+	collect := func(x int32) bool {
+		got = append(got, x)
+		return true
+	}
+	b.RangeBetween(-200, 40, collect)
+	if !reflect.DeepEqual(got, []int32{-100, -3, 40}) {
+		t.Fatalf("RangeBetween(-200,40) = %v", got)
+	}
+	got = nil
+	b.RangeReverse(collect)
+	if !reflect.DeepEqual(got, []int32{100, 40, -3, -100}) {
+		t.Fatalf("RangeReverse() = %v", got)
+	}
+	got = nil
+	b.RangeReverseBetween(-3, 99, collect)
+	if !reflect.DeepEqual(got, []int32{40, -3}) {
+		t.Fatalf("RangeReverseBetween(-3,99) = %v", got)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	const max = 1000
+	items := []uint32{0, 3, 15, 16, 31, 32, 64, 500, 999, 1000}
+	for _, s := range [...]interface {
+		Interface
+		Iterator() *set.Iterator
+	}{
+		&set.Static{},
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			s.OnceInit(max)
+			set.Adds(s, items...)
+			it := s.Iterator()
+			var got []uint32
+			for x, ok := it.Next(); ok; x, ok = it.Next() {
+				got = append(got, x)
+			}
+			if !reflect.DeepEqual(got, items) {
+				t.Fatalf("Next() = %v want %v", got, items)
+			}
+			if _, ok := it.Next(); ok {
+				t.Fatalf("Next() after end ok")
+			}
+			it.Seek(33)
+			if x, ok := it.Next(); !ok || x != 64 {
+				t.Fatalf("Seek(33) Next() = %d,%v want 64", x, ok)
+			}
+			// deleted ahead of the cursor is not visit.
+			s.Delete(500)
+			if x, ok := it.Next(); !ok || x != 999 {
+				t.Fatalf("Next() = %d,%v want 999", x, ok)
+			}
+			it.Reset()
+			if x, ok := it.Next(); !ok || x != 0 {
+				t.Fatalf("Reset() Next() = %d,%v want 0", x, ok)
+			}
+		})
+	}
+}
+
+func TestIteratorMerge(t *testing.T) {
+	var x, y set.Static
+	x.OnceInit(100)
+	y.OnceInit(100)
+	set.Adds(&x, 1, 3, 5, 60)
+	set.Adds(&y, 2, 3, 70)
+	xi, yi := x.Iterator(), y.Iterator()
+	var got []uint32
+	a, aok := xi.Next()
+	b, bok := yi.Next()
+	for aok || bok {
+		switch {
+		case !bok || aok && a < b:
+			got = append(got, a)
+			a, aok = xi.Next()
+		case !aok || b < a:
+			got = append(got, b)
+			b, bok = yi.Next()
+		default:
+			got = append(got, a)
+			a, aok = xi.Next()
+			b, bok = yi.Next()
+		}
+	}
+	if want := set.Items(set.Union(&x, &y)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("merge = %v want %v", got, want)
+	}
+}
+
+func TestIteratorGrow(t *testing.T) {
+	var s set.Dynamic
+	s.OnceInit(0)
+	s.Store(1)
+	s.Store(2)
+	it := s.Iterator()
+	if x, ok := it.Next(); !ok || x != 1 {
+		t.Fatalf("Next() = %d,%v want 1", x, ok)
+	}
+	// grow while iterate.
+	for i := uint32(100); i < 1<<16; i += 100 {
+		s.Store(i)
+	}
+	want := uint32(2)
+	for x, ok := it.Next(); ok; x, ok = it.Next() {
+		if x != want {
+			t.Fatalf("Next() = %d want %d", x, want)
+		}
+		if want == 2 {
+			want = 100
+		} else {
+			want += 100
+		}
+	}
+	if want < 1<<16 {
+		t.Fatalf("stop at %d", want)
+	}
+}
+
+func TestBaseIterator(t *testing.T) {
+	b := set.NewBase(100, -100)
+	for _, x := range []int32{-100, -3, 40, 100} {
+		b.Add(x)
+	}
+	it := b.Iterator()
+	var got []int32
+	for x, ok := it.Next(); ok; x, ok = it.Next() {
+		got = append(got, x)
+	}
+	if !reflect.DeepEqual(got, []int32{-100, -3, 40, 100}) {
+		t.Fatalf("Next() = %v", got)
+	}
+	it.Seek(-2)
+	if x, ok := it.Next(); !ok || x != 40 {
+		t.Fatalf("Seek(-2) Next() = %d,%v want 40", x, ok)
+	}
+	it.Reset()
+	if x, ok := it.Next(); !ok || x != -100 {
+		t.Fatalf("Reset() Next() = %d,%v want -100", x, ok)
+	}
+}
+
+func TestRangeWords(t *testing.T) {
+	items := []uint32{0, 3, 15, 16, 31, 32, 63, 64, 500, 999, 1000}
+	for _, s := range [...]interface {
+		Interface
+		RangeWords(f func(base uint32, word uint64) bool)
+	}{
+		&set.Static{},
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			s.OnceInit(1000)
+			set.Adds(s, items...)
+			var got []uint32
+			s.RangeWords(func(base uint32, word uint64) bool {
+				if base%64 != 0 || word == 0 {
+					t.Fatalf("RangeWords() base=%d word=%x", base, word)
+				}
+				for ; word != 0; word &= word - 1 {
+					got = append(got, base+uint32(bits.TrailingZeros64(word)))
+				}
+				return true
+			})
+			if !reflect.DeepEqual(got, items) {
+				t.Fatalf("RangeWords() = %v want %v", got, items)
+			}
+			n := 0
+			s.RangeWords(func(base uint32, word uint64) bool {
+				n++
+				return false
+			})
+			if n != 1 {
+				t.Fatalf("RangeWords() not stop: %d", n)
+			}
+		})
+	}
+}
+
+type rankSet interface {
+	Interface
+	EnableRank()
+	Rank(x uint32) int
+	Select(k int) (uint32, bool)
+	CountRange(lo, hi uint32) int
+}
+
+func TestRankSelect(t *testing.T) {
+	const max = 10000
+	r := rand.New(rand.NewSource(1))
+	items := make([]uint32, 0, max)
+	for i := 0; i < max; i++ {
+		if r.Intn(3) == 0 {
+			items = append(items, uint32(i))
+		}
+	}
+	for _, index := range []bool{false, true} {
+		for _, s := range [...]rankSet{
+			&set.Static{},
+			&set.Dynamic{},
+		} {
+			t.Run(fmt.Sprintf("%T/index=%v", s, index), func(t *testing.T) {
+				s.OnceInit(max)
+				if index {
+					s.EnableRank()
+				}
+				set.Adds(s, items...)
+				// delete keep the index.
+				s.Delete(items[0])
+				s.Store(items[0])
+				for k, x := range items {
+					if got := s.Rank(x); got != k+1 {
+						t.Fatalf("Rank(%d) = %d, want %d", x, got, k+1)
+					}
+					if got, ok := s.Select(k); !ok || got != x {
+						t.Fatalf("Select(%d) = %d,%v want %d", k, got, ok, x)
+					}
+				}
+				if _, ok := s.Select(len(items)); ok {
+					t.Fatalf("Select(%d) ok", len(items))
+				}
+				if got := s.Rank(max * 2); got != len(items) {
+					t.Fatalf("Rank(max) = %d, want %d", got, len(items))
+				}
+				for i := 0; i < 1000; i++ {
+					lo, hi := uint32(r.Intn(max)), uint32(r.Intn(max))
+					want := 0
+					for _, x := range items {
+						if lo <= x && x <= hi {
+							want += 1
+						}
+					}
+					if got := s.CountRange(lo, hi); got != want {
+						t.Fatalf("CountRange(%d,%d) = %d, want %d", lo, hi, got, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestConcurrentRank(t *testing.T) {
+	var wg sync.WaitGroup
+	goNum := runtime.NumCPU()
+	const max = 10000
+	for _, s := range [...]rankSet{
+		&set.Static{},
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			s.OnceInit(0)
+			s.EnableRank()
+			wg.Add(goNum)
+			for i := 0; i < goNum; i++ {
+				go func(i int) {
+					defer wg.Done()
+					for x := i; x < max; x += goNum {
+						s.Store(uint32(x))
+						if x%2 == 1 {
+							s.Delete(uint32(x))
+						}
+					}
+				}(i)
+			}
+			wg.Wait()
+			if got, want := s.Rank(max), set.Size(s); got != want {
+				t.Fatalf("Rank(max) = %d, Size() = %d", got, want)
+			}
+			for k := 0; k < set.Size(s); k++ {
+				if x, ok := s.Select(k); !ok || x != uint32(2*k) {
+					t.Fatalf("Select(%d) = %d,%v want %d", k, x, ok, 2*k)
+				}
+			}
+		})
+	}
+}
+
+type rangeSet interface {
+	Interface
+	AddRange(lo, hi uint32) bool
+	RemoveRange(lo, hi uint32) bool
+	FlipRange(lo, hi uint32) bool
+}
+
+func TestUpdateRange(t *testing.T) {
+	const max = 5000
+	r := rand.New(rand.NewSource(1))
+	for _, s := range [...]rangeSet{
+		&set.Static{},
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			s.OnceInit(max)
+			want := make(map[uint32]bool)
+			for i := 0; i < 200; i++ {
+				lo, hi := uint32(r.Intn(max)), uint32(r.Intn(max))
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				switch i % 3 {
+				case 0:
+					s.AddRange(lo, hi)
+					for x := lo; x <= hi; x++ {
+						want[x] = true
+					}
+				case 1:
+					s.RemoveRange(lo, hi)
+					for x := lo; x <= hi; x++ {
+						delete(want, x)
+					}
+				case 2:
+					s.FlipRange(lo, hi)
+					for x := lo; x <= hi; x++ {
+						if want[x] {
+							delete(want, x)
+						} else {
+							want[x] = true
+						}
+					}
+				}
+			}
+			for x := uint32(0); x <= max; x++ {
+				if s.Load(x) != want[x] {
+					t.Fatalf("Load(%d) = %v, want %v", x, s.Load(x), want[x])
+				}
+			}
+			if got := set.Size(s); got != len(want) {
+				t.Fatalf("Size() = %d, want %d", got, len(want))
+			}
+			if s.AddRange(0, max+1) {
+				t.Fatalf("AddRange overflow ok")
+			}
+		})
+	}
+}
+
+func TestDynamicAddRangeGrow(t *testing.T) {
+	var wg sync.WaitGroup
+	goNum := runtime.NumCPU()
+	var s set.Dynamic
+	wg.Add(goNum)
+	for i := 0; i < goNum; i++ {
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				lo := uint32((j*goNum + i) * 100)
+				s.AddRange(lo, lo+99)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got, want := set.Size(&s), goNum*100*100; got != want {
+		t.Fatalf("Size() = %d, want %d", got, want)
+	}
+	if x, ok := s.Max(); !ok || x != uint32(goNum*100*100-1) {
+		t.Fatalf("Max() = %d,%v", x, ok)
+	}
+}
+
+func TestConcurrentStoreMany(t *testing.T) {
+	var wg sync.WaitGroup
+	goNum := runtime.NumCPU()
+	var s set.Dynamic
+	wg.Add(goNum)
+	for i := 0; i < goNum; i++ {
+		go func(i int) {
+			defer wg.Done()
+			xs := make([]uint32, 0, 1000)
+			for j := 0; j < 1000; j++ {
+				xs = append(xs, uint32((j*goNum+i)*3))
+			}
+			s.StoreMany(xs)
+		}(i)
+	}
+	wg.Wait()
+	if n := set.Size(&s); n != goNum*1000 {
+		t.Fatalf("Size() = %d want %d", n, goNum*1000)
+	}
+	for i := 0; i < goNum*1000; i++ {
+		if !s.Load(uint32(i * 3)) {
+			t.Fatalf("not store:%d", i*3)
+		}
+	}
+}
+
+func TestBaseRange(t *testing.T) {
+	b := set.NewBase(100, -100)
+	b.AddRange(-50, 50)
+	b.RemoveRange(-10, 10)
+	b.FlipRange(0, 60)
+	n := 0
+	b.Range(func(x int32) bool {
+		in := (x >= -50 && x < -10) || (x >= 0 && x <= 10) || (x > 50 && x <= 60)
+		if !in {
+			t.Fatalf("Range has %d", x)
+		}
+		n += 1
+		return true
+	})
+	if n != 40+11+10 {
+		t.Fatalf("Range count %d", n)
+	}
+	if b.AddRange(-101, 0) {
+		t.Fatalf("AddRange overflow ok")
+	}
+}
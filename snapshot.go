@@ -0,0 +1,261 @@
+package set
+
+import (
+	"math/bits"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Snapshot is an immutable set of the items at a single instant,
+// return by Static.Snapshot and Dynamic.Snapshot.
+// it implements Set, but Store and Delete always fail as overflow.
+// Snapshot is safe for concurrent use and never change.
+type Snapshot struct {
+	// frozen *dynEntry or a clone *Static never write.
+	bits bitSet
+	max  uint32
+
+	// number of items, 0 if not count yet.
+	count uint32
+}
+
+// the writers of word i enter gate (i>>gateShift)%gateStripes,
+// so the writers of far away words not share a cache line.
+const (
+	gateShift   = 10
+	gateStripes = 16
+)
+
+// snapGates is the writer gates of a Static, create by the first Snapshot.
+type snapGates struct {
+	// 1 while a snapshot is taking, the writers read it before enter a gate.
+	snap uint32
+
+	gates [gateStripes]struct {
+		// number of writers in progress.
+		n uint32
+		_ [60]byte
+	}
+}
+
+// the writers of a set never snapshot or clone pass no gate:
+// cas is go:nosplit, it has no preemption point after load the gates,
+// and the first Snapshot or Clone stop the world once,
+// which wait the writers in it done.
+// a clone has gates from the start, its table is shared.
+
+// cas CAS word i from old to new and add the bits changed to count,
+// in the writer gate if any.
+// the caller check new != old first, a no-op write not copy a shared page.
+//
+//go:nosplit
+//go:noinline
+func (s *Static) cas(i int, old, new uint32) bool {
+	// count before load the gates, OnesCount32 may call.
+	delta := bits.OnesCount32(new) - bits.OnesCount32(old)
+	if atomic.LoadPointer(&s.gates) != nil {
+		return s.casGated(i, old, new, delta)
+	}
+	// never cloned, the table and the pages are owned.
+	t := (*staticPages)(atomic.LoadPointer(&s.pages))
+	p := (*uint32)(unsafe.Add(atomic.LoadPointer(&t.pages[i>>pageShift]), (i&pageMask)*4))
+	if !atomic.CompareAndSwapUint32(p, old, new) {
+		return false
+	}
+	atomic.AddUint32(&s.count, uint32(delta))
+	return true
+}
+
+func (s *Static) casGated(i int, old, new uint32, delta int) bool {
+	g := s.enter(i)
+	swapped := atomic.CompareAndSwapUint32(s.writable(i), old, new)
+	if swapped {
+		atomic.AddUint32(&s.count, uint32(delta))
+	}
+	s.leave(g)
+	return swapped
+}
+
+// enter enter the writer gate of word i, wait the snapshot taking done.
+func (s *Static) enter(i int) *uint32 {
+	p := (*snapGates)(atomic.LoadPointer(&s.gates))
+	g := &p.gates[(i>>gateShift)%gateStripes].n
+	for {
+		if atomic.LoadUint32(&p.snap) == 0 {
+			atomic.AddUint32(g, 1)
+			if atomic.LoadUint32(&p.snap) == 0 {
+				return g
+			}
+			// snapshot taking, back off.
+			atomic.AddUint32(g, ^uint32(0))
+		}
+		runtime.Gosched()
+	}
+}
+
+// leave exit the writer gate g return by enter.
+func (s *Static) leave(g *uint32) { atomic.AddUint32(g, ^uint32(0)) }
+
+// lockGate close the writer gates, and wait the writers in progress done,
+// the new writers wait until unlockGate.
+func (s *Static) lockGate() *snapGates {
+	p := (*snapGates)(atomic.LoadPointer(&s.gates))
+	if p == nil {
+		np := &snapGates{snap: 1}
+		if atomic.CompareAndSwapPointer(&s.gates, nil, unsafe.Pointer(np)) {
+			// the new writers see the gates wait, ReadMemStats stop
+			// the world, which wait the writers in the fast functions done.
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			return np
+		}
+		p = (*snapGates)(atomic.LoadPointer(&s.gates))
+	}
+	for !atomic.CompareAndSwapUint32(&p.snap, 0, 1) {
+		// other snapshot taking.
+		runtime.Gosched()
+	}
+	for k := range p.gates {
+		for atomic.LoadUint32(&p.gates[k].n) != 0 {
+			runtime.Gosched()
+		}
+	}
+	return p
+}
+
+// unlockGate open the writer gates.
+func (s *Static) unlockGate(p *snapGates) { atomic.StoreUint32(&p.snap, 0) }
+
+// Snapshot return an immutable copy of the set at a single instant.
+// Snapshot close the writer gate and wait the writers in progress done,
+// then share the pages as Clone, the new writers wait until the pages shared.
+// the first Snapshot of a set also stop the world once,
+// the writers of a set never snapshot pass no gate.
+// Load never wait.
+// time complexity: O(1)
+func (s *Static) Snapshot() *Snapshot {
+	p := s.Clone()
+	return &Snapshot{bits: p, max: p.getMax(), count: atomic.LoadUint32(&p.count)}
+}
+
+// Snapshot return an immutable copy of the set at a single instant.
+// Snapshot freeze all words as growing, the frozen node become the snapshot,
+// and a copy of it is install as the new node.
+// the writers wait until the new node install, Load never wait.
+// time complexity: O(N/16)
+func (s *Dynamic) Snapshot() *Snapshot {
+	s.OnceInit(0)
+	for {
+		e := s.getEntry()
+		if !atomic.CompareAndSwapUint32(&e.resize, 0, 1) {
+			// other thread growing or taking snapshot
+			runtime.Gosched()
+			continue
+		}
+		ne := &dynEntry{cap: e.getCap(), data: make([]uint32, e.getCap())}
+		count := 0
+		for i := 0; i < int(e.getCap()); i++ {
+			if item := e.freeze(i) &^ freezeBit; item != 0 {
+				ne.store(i, item)
+				count += bits.OnesCount32(item)
+			}
+		}
+		// all words frozen, len not change any more.
+		if n := e.getLen(); n > ne.len {
+			ne.len = n
+		}
+		if atomic.LoadUint32(&s.ranked) == 1 {
+			ne.rank = unsafe.Pointer(newRankIndex(ne))
+		}
+		// fail only if the set is cleared, the snapshot is still before clear.
+		atomic.CompareAndSwapPointer(&s.node, unsafe.Pointer(e), unsafe.Pointer(ne))
+		return &Snapshot{bits: e, max: s.getMax(), count: uint32(count)}
+	}
+}
+
+// OnceInit do nothing, snapshot is init when taking.
+func (s *Snapshot) OnceInit(max int) {}
+
+// Load reports whether the snapshot contains the non-negative value x.
+// time complexity: O(1)
+func (s *Snapshot) Load(x uint32) bool {
+	if x > s.max {
+		return false
+	}
+	w := s.bits.wordBits()
+	idx := int(x / w)
+	if idx >= int(s.bits.getLen()) {
+		return false
+	}
+	return (s.bits.load(idx)>>(x%w))&1 == 1
+}
+
+// Store always return false, snapshot is immutable.
+func (s *Snapshot) Store(x uint32) bool { return false }
+
+// Delete always return false, snapshot is immutable.
+func (s *Snapshot) Delete(x uint32) bool { return false }
+
+// LoadOrStore report x if in snapshot, ok always false, snapshot is immutable.
+func (s *Snapshot) LoadOrStore(x uint32) (loaded, ok bool) { return s.Load(x), false }
+
+// LoadAndDelete report x if in snapshot, ok always false, snapshot is immutable.
+func (s *Snapshot) LoadAndDelete(x uint32) (loaded, ok bool) { return s.Load(x), false }
+
+// Range calls f sequentially for each item in the snapshot, in ascending order.
+// If f returns false, range stops the iteration.
+// time complexity: O(N/32)
+func (s *Snapshot) Range(f func(x uint32) bool) {
+	walkBetween(s.bits, 0, s.max, f)
+}
+
+// RangeWords calls f sequentially for each 64 items word of the snapshot,
+// base is the first item of word, bit j of word is item base+j.
+// the empty words are skipped.
+// If f returns false, range stops the iteration.
+// time complexity: O(N/64)
+func (s *Snapshot) RangeWords(f func(base uint32, word uint64) bool) {
+	walkWords(s.bits, f)
+}
+
+// RangeBetween calls f sequentially for each item in [lo,hi] of the snapshot.
+// If f returns false, range stops the iteration.
+func (s *Snapshot) RangeBetween(lo, hi uint32, f func(x uint32) bool) {
+	walkBetween(s.bits, lo, hi, f)
+}
+
+// Len return the number of items in the snapshot.
+// time complexity: O(1), O(N/32) at the first time if not count yet.
+func (s *Snapshot) Len() int {
+	n := atomic.LoadUint32(&s.count)
+	if n == 0 {
+		n = uint32(popCount(s.bits))
+		atomic.StoreUint32(&s.count, n)
+	}
+	return int(n)
+}
+
+// Min return the smallest item in the snapshot.
+// ok is false if the snapshot is empty.
+func (s *Snapshot) Min() (x uint32, ok bool) { return nextBit(s.bits, 0) }
+
+// Max return the biggest item in the snapshot.
+// ok is false if the snapshot is empty.
+func (s *Snapshot) Max() (x uint32, ok bool) { return prevBit(s.bits, s.max) }
+
+// Next return the smallest item in the snapshot at or after x.
+// ok is false if no such item.
+func (s *Snapshot) Next(x uint32) (next uint32, ok bool) {
+	if x > s.max {
+		return 0, false
+	}
+	return nextBit(s.bits, x)
+}
+
+// Prev return the biggest item in the snapshot at or before x.
+// ok is false if no such item.
+func (s *Snapshot) Prev(x uint32) (prev uint32, ok bool) { return prevBit(s.bits, x) }
+
+// String returns the snapshot as a string of the form "{1 2 3}".
+func (s *Snapshot) String() string { return String(s) }
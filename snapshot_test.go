@@ -0,0 +1,147 @@
+package set_test
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+type snapshotSet interface {
+	set.Set
+	Snapshot() *set.Snapshot
+}
+
+func TestSnapshot(t *testing.T) {
+	for _, s := range []snapshotSet{
+		set.NewStatic(1000).(*set.Static),
+		set.NewDynamic(1000).(*set.Dynamic),
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			set.Adds(s, 1, 2, 64, 999)
+			snap := s.Snapshot()
+			s.Delete(2)
+			s.Store(500)
+			if got := snap.String(); got != "{1 2 64 999}" {
+				t.Fatalf("Snapshot() = %v", got)
+			}
+			if set.Size(snap) != 4 || snap.Len() != 4 {
+				t.Fatalf("Snapshot() size = %d", set.Size(snap))
+			}
+			if !snap.Load(2) || snap.Load(500) || snap.Load(1001) {
+				t.Fatalf("Snapshot() Load err")
+			}
+			if snap.Store(3) || snap.Delete(1) || !snap.Load(1) {
+				t.Fatalf("Snapshot() not immutable")
+			}
+			if x, ok := snap.Max(); !ok || x != 999 {
+				t.Fatalf("Snapshot() Max = %d %v", x, ok)
+			}
+			if got := set.String(s); got != "{1 64 500 999}" {
+				t.Fatalf("set after Snapshot() = %v", got)
+			}
+			// copy is mutable.
+			p := set.Copy(snap)
+			if !p.Store(3) || !set.Equal(p, set.NewStatic(1000, 1, 2, 3, 64, 999)) {
+				t.Fatalf("Copy(Snapshot()) = %v", p)
+			}
+			if u := set.Union(snap, s.Snapshot()); !set.Equal(u, set.NewStatic(1000, 1, 2, 64, 500, 999)) {
+				t.Fatalf("Union(Snapshot()) = %v", u)
+			}
+		})
+	}
+}
+
+// tokenOK report items is one token or two adjacent tokens of pos.
+func tokenOK(items, pos []uint32) bool {
+	if len(items) == 1 {
+		return true
+	}
+	if len(items) == 2 {
+		for k := range pos {
+			a, b := pos[k], pos[(k+1)%len(pos)]
+			if (a == items[0] && b == items[1]) || (a == items[1] && b == items[0]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TestSnapshotConsistent move a token between far away words,
+// store the next one before delete the current one,
+// each snapshot must see one token or two adjacent tokens.
+func TestSnapshotConsistent(t *testing.T) {
+	pos := []uint32{0, 70000, 31, 150000, 64, 100000}
+	for _, s := range []snapshotSet{
+		set.NewStatic(200000).(*set.Static),
+		&set.Dynamic{},
+	} {
+		t.Run(fmt.Sprintf("%T", s), func(t *testing.T) {
+			s.Store(pos[0])
+			var stop, moves uint32
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for k := 0; atomic.LoadUint32(&stop) == 0; k++ {
+					cur, next := pos[k%len(pos)], pos[(k+1)%len(pos)]
+					s.Store(next)
+					s.Delete(cur)
+					atomic.AddUint32(&moves, 1)
+				}
+			}()
+			go func() {
+				// Load never block.
+				defer wg.Done()
+				for atomic.LoadUint32(&stop) == 0 {
+					s.Load(pos[1])
+				}
+			}()
+			// make sure the writer has run.
+			for n := 0; n < 2000 || atomic.LoadUint32(&moves) < 10000; n++ {
+				items := set.Items(s.Snapshot())
+				if !tokenOK(items, pos) {
+					atomic.StoreUint32(&stop, 1)
+					wg.Wait()
+					t.Fatalf("Snapshot() = %v not consistent", items)
+				}
+			}
+			atomic.StoreUint32(&stop, 1)
+			wg.Wait()
+		})
+	}
+}
+
+// TestSnapshotFirst take the first snapshot of a set while a writer
+// move the token, the writers pass no gate before it.
+func TestSnapshotFirst(t *testing.T) {
+	pos := []uint32{0, 70000, 31, 150000, 64, 100000}
+	for n := 0; n < 50; n++ {
+		s := set.NewStatic(200000).(*set.Static)
+		s.Store(pos[0])
+		var stop, moves uint32
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for k := 0; atomic.LoadUint32(&stop) == 0; k++ {
+				s.Store(pos[(k+1)%len(pos)])
+				s.Delete(pos[k%len(pos)])
+				atomic.AddUint32(&moves, 1)
+			}
+		}()
+		for atomic.LoadUint32(&moves) < uint32(n%7*100) {
+			runtime.Gosched()
+		}
+		items := set.Items(s.Snapshot())
+		atomic.StoreUint32(&stop, 1)
+		<-done
+		if !tokenOK(items, pos) {
+			t.Fatalf("first Snapshot() = %v not consistent", items)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package set
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+)
+
+// Value implements the driver.Valuer interface,
+// the set is store as the compact binary of MarshalBinary.
+// use AsText to store in run notation text.
+func (s *Static) Value() (driver.Value, error) { return s.MarshalBinary() }
+
+// Scan implements the sql.Scanner interface.
+// src can be the binary of MarshalBinary or the run notation text,
+// NULL is load as empty set.
+// the set is init with max of data if not init yet, and cleared before load.
+func (s *Static) Scan(src interface{}) error { return scanSQL(s, src) }
+
+// Value implements the driver.Valuer interface,
+// the set is store as the compact binary of MarshalBinary.
+// use AsText to store in run notation text.
+func (s *Dynamic) Value() (driver.Value, error) { return s.MarshalBinary() }
+
+// Scan implements the sql.Scanner interface.
+// src can be the binary of MarshalBinary or the run notation text,
+// NULL is load as empty set.
+// the set is init with max of data if not init yet, and cleared before load.
+func (s *Dynamic) Scan(src interface{}) error { return scanSQL(s, src) }
+
+// Value implements the driver.Valuer interface,
+// the set is store as the compact binary of MarshalBinary.
+// use AsText to store in run notation text.
+func (s *Base) Value() (driver.Value, error) { return s.MarshalBinary() }
+
+// Scan implements the sql.Scanner interface.
+// src can be the binary of MarshalBinary or the run notation text,
+// NULL is load as empty set.
+// the set is init with max and min of data if not init yet, and cleared before load.
+func (s *Base) Scan(src interface{}) error { return scanSQL(s, src) }
+
+// TextValue is a sql column value of a set in run notation text,
+// like {1-5 9 12-20}.
+type TextValue struct{ s interface{} }
+
+// AsText wrap a Static, Dynamic or Base, store it in sql column
+// as run notation text, for the text column or human readable.
+//
+//	db.Exec("UPDATE t SET tags = ? WHERE id = ?", set.AsText(s), id)
+//	db.QueryRow("SELECT tags FROM t WHERE id = ?", id).Scan(set.AsText(s))
+func AsText(s interface{}) TextValue { return TextValue{s} }
+
+// Value implements the driver.Valuer interface.
+func (v TextValue) Value() (driver.Value, error) {
+	text, err := marshalText(v.s)
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// Scan implements the sql.Scanner interface,
+// src can be the run notation text or binary.
+func (v TextValue) Scan(src interface{}) error { return scanSQL(v.s, src) }
+
+// scanSQL load the binary or text of src to s.
+func scanSQL(s interface{}, src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return loadRuns(s, nil, true)
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("set: can't scan %T into %T", src, s)
+	}
+	if bytes.HasPrefix(data, []byte(binaryMagic)) {
+		u, ok := s.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return fmt.Errorf("set: can't scan binary into %T", s)
+		}
+		return u.UnmarshalBinary(data)
+	}
+	return unmarshalText(s, string(data), true)
+}
@@ -0,0 +1,171 @@
+package set_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+// memDriver is a fake sql driver of a key value table,
+// "put" with args key,value and "get" with arg key.
+type memDriver struct {
+	mu   sync.Mutex
+	data map[string]driver.Value
+}
+
+type memConn struct{ d *memDriver }
+
+type memStmt struct {
+	c     *memConn
+	query string
+}
+
+type memRows struct {
+	v    driver.Value
+	done bool
+}
+
+func (d *memDriver) Open(string) (driver.Conn, error) { return &memConn{d}, nil }
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) { return &memStmt{c, query}, nil }
+func (c *memConn) Close() error                              { return nil }
+func (c *memConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not support") }
+
+func (s *memStmt) Close() error  { return nil }
+func (s *memStmt) NumInput() int { return -1 }
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.query != "put" || len(args) != 2 {
+		return nil, errors.New("bad exec " + s.query)
+	}
+	if b, ok := args[1].([]byte); ok {
+		args[1] = append([]byte(nil), b...)
+	}
+	s.c.d.mu.Lock()
+	defer s.c.d.mu.Unlock()
+	s.c.d.data[args[0].(string)] = args[1]
+	return driver.RowsAffected(1), nil
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.query != "get" || len(args) != 1 {
+		return nil, errors.New("bad query " + s.query)
+	}
+	s.c.d.mu.Lock()
+	defer s.c.d.mu.Unlock()
+	return &memRows{v: s.c.d.data[args[0].(string)]}, nil
+}
+
+func (r *memRows) Columns() []string { return []string{"v"} }
+func (r *memRows) Close() error      { return nil }
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.v
+	return nil
+}
+
+var memData = &memDriver{data: make(map[string]driver.Value)}
+
+func init() {
+	sql.Register("setmem", memData)
+}
+
+func TestSQL(t *testing.T) {
+	db, err := sql.Open("setmem", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s := set.NewStatic(1000, 1, 2, 3, 4, 5, 9, 999)
+	d := set.NewDynamic(1000, 1, 2, 3, 4, 5, 9, 999)
+	b := set.NewBase(100, -100)
+	for _, x := range []int32{-100, -5, 0, 7, 100} {
+		b.Add(x)
+	}
+	for _, v := range []interface{}{s, d, b} {
+		if _, err := db.Exec("put", "bin", v); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := memData.data["bin"].([]byte); !ok {
+			t.Fatalf("Value() %T = %T want []byte", v, memData.data["bin"])
+		}
+		if _, err := db.Exec("put", "text", set.AsText(v)); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"bin", "text"} {
+			switch v := v.(type) {
+			case *set.Base:
+				var got set.Base
+				if err := db.QueryRow("get", key).Scan(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got.String() != v.String() {
+					t.Fatalf("Scan(%s) = %v want %v", key, &got, v)
+				}
+			default:
+				for _, got := range []set.Set{&set.Static{}, &set.Dynamic{}} {
+					if err := db.QueryRow("get", key).Scan(got); err != nil {
+						t.Fatal(err)
+					}
+					if !set.Equal(got, v.(set.Set)) {
+						t.Fatalf("Scan(%s) = %v want %v", key, got, v)
+					}
+				}
+			}
+		}
+	}
+	if got := memData.data["text"]; got != "{-100 -5 0 7 100}" {
+		t.Fatalf("AsText() = %v", got)
+	}
+
+	// AsText scan binary too.
+	var got set.Static
+	db.Exec("put", "bin", s)
+	if err := db.QueryRow("get", "bin").Scan(set.AsText(&got)); err != nil || !set.Equal(&got, s) {
+		t.Fatalf("AsText Scan() = %v %v", &got, err)
+	}
+	// Base binary can't scan to Static.
+	db.Exec("put", "bin", b)
+	if err := db.QueryRow("get", "bin").Scan(&got); !errors.Is(err, set.ErrKind) {
+		t.Fatalf("Scan(Base) = %v", err)
+	}
+	// NULL is empty set.
+	if _, err := db.Exec("put", "null", nil); err != nil {
+		t.Fatal(err)
+	}
+	got.Store(1)
+	if err := db.QueryRow("get", "null").Scan(&got); err != nil || set.Size(&got) != 0 {
+		t.Fatalf("Scan(NULL) = %v %v", &got, err)
+	}
+	if err := got.Scan(1.5); err == nil {
+		t.Fatalf("Scan(float) no error")
+	}
+}
+
+// TestScanMax the max of set is kept across Value and Scan.
+func TestScanMax(t *testing.T) {
+	var st set.Static
+	st.OnceInit(2000000)
+	st.Store(7)
+	v, err := st.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s set.Static
+	if err := s.Scan(v); err != nil || s.String() != "{7}" {
+		t.Fatalf("Scan() = %v %v", &s, err)
+	}
+	if !s.Store(2000000) || s.Store(2000001) {
+		t.Fatalf("Scan() max not kept")
+	}
+}
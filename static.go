@@ -1,8 +1,10 @@
 package set
 
 import (
+	"math/bits"
 	"sync"
 	"sync/atomic"
+	"unsafe"
 )
 
 // Static a set of non-negative integers.
@@ -30,7 +32,18 @@ type Static struct {
 	// len(items),idx cursor
 	len uint32
 
-	data []uint32
+	// *staticPages, words in pages shared by clones.
+	pages unsafe.Pointer
+
+	// lock of copy on write pages.
+	mu sync.Mutex
+
+	// *rankIndex, nil if rank index not enable.
+	rank unsafe.Pointer
+
+	// *snapGates, writer gates of Snapshot and Clone,
+	// nil if never take a snapshot or clone.
+	gates unsafe.Pointer
 }
 
 func (s *Static) onceInit(max int) {
@@ -42,7 +55,7 @@ func (s *Static) onceInit(max int) {
 			max = int(maximum)
 		}
 		num := max>>5 + 1
-		s.data = make([]uint32, num)
+		atomic.StorePointer(&s.pages, unsafe.Pointer(newPages(num)))
 		atomic.StoreUint32(&s.cap, uint32(num))
 		atomic.StoreUint32(&s.max, uint32(max))
 	})
@@ -60,13 +73,29 @@ func (s *Static) Init() { s.onceInit(initSize) }
 func (s *Static) getLen() uint32    { return atomic.LoadUint32(&s.len) }
 func (s *Static) getCap() uint32    { return atomic.LoadUint32(&s.cap) }
 func (s *Static) getMax() uint32    { return atomic.LoadUint32(&s.max) }
-func (s *Static) load(i int) uint32 { return atomic.LoadUint32(&s.data[i]) }
+func (s *Static) load(i int) uint32 { return atomic.LoadUint32(s.getPages().word(i)) }
+func (s *Static) wordBits() uint32  { return 32 }
 
 func (s *Static) store(i int, x uint32) {
 	if s.overflow(i) {
 		return
 	}
-	atomic.StoreUint32(&s.data[i], x)
+	s.modify(i, func(uint32) uint32 { return x })
+}
+
+// account add delta to rank index after word i changed,
+// the count is add by cas.
+func (s *Static) account(i int, delta int) {
+	if delta == 0 {
+		return
+	}
+	if r := s.getRank(); r != nil {
+		r.add(i, delta)
+	}
+}
+
+func (s *Static) getRank() *rankIndex {
+	return (*rankIndex)(atomic.LoadPointer(&s.rank))
 }
 
 // in 64 bit platform
@@ -83,17 +112,12 @@ func (s *Static) idxMod(x uint32) (idx, mod int) {
 // Load reports whether the set contains the non-negative value x.
 // time complexity: O(1)
 func (s *Static) Load(x uint32) bool {
-	if x > s.getMax() {
-		// overflow
-		return false
-	}
-	idx, mod := s.idxMod(x)
-	if idx >= int(s.getLen()) {
-		// not in set
+	idx := int(x >> 5)
+	if x > s.getMax() || idx >= int(s.getLen()) {
+		// overflow or not in set
 		return false
 	}
-	item := s.load(idx)
-	return (item>>mod)&1 == 1
+	return (s.load(idx)>>(x&31))&1 == 1
 }
 
 // Store adds the non-negative value x to the set.
@@ -124,13 +148,47 @@ func (s *Static) LoadOrStore(x uint32) (loaded, ok bool) {
 			// already in set
 			return true, true
 		}
-		if atomic.CompareAndSwapUint32(&s.data[idx], item, item|(1<<mod)) {
-			atomic.AddUint32(&s.count, 1)
+		if s.cas(idx, item, item|(1<<mod)) {
+			s.account(idx, 1)
 			return false, true
 		}
 	}
 }
 
+// StoreMany adds the items xs to the set.
+// the items of a word are build locally, and publish with one atomic CAS,
+// so sorted xs only cost one atomic op per word.
+// return false if some x overflow bigger than max, the others still stored.
+// time complexity: O(len(xs))
+func (s *Static) StoreMany(xs []uint32) bool {
+	s.onceInit(initSize)
+	max := s.getMax()
+	ok := true
+	idx, item := -1, uint32(0)
+	for _, x := range xs {
+		if x > max {
+			ok = false
+			continue
+		}
+		i, mod := s.idxMod(x)
+		if i != idx {
+			s.orWord(idx, item)
+			idx, item = i, 0
+		}
+		item |= 1 << mod
+	}
+	s.orWord(idx, item)
+	return ok
+}
+
+// orWord set the bits of item in word i with one atomic CAS.
+func (s *Static) orWord(i int, item uint32) {
+	if item == 0 || s.overflow(i) {
+		return
+	}
+	s.modify(i, func(old uint32) uint32 { return old | item })
+}
+
 // Delete remove x from the set
 // return true if success, false if x overflow
 // time complexity: O(1)
@@ -158,8 +216,8 @@ func (s *Static) LoadAndDelete(x uint32) (loaded, ok bool) {
 		if (item>>mod)&1 == 0 {
 			return false, true
 		}
-		if atomic.CompareAndSwapUint32(&s.data[idx], item, item&^(1<<mod)) {
-			atomic.AddUint32(&s.count, ^uint32(0))
+		if s.cas(idx, item, item&^(1<<mod)) {
+			s.account(idx, -1)
 			return true, true
 		}
 	}
@@ -171,29 +229,233 @@ func (s *Static) LoadAndDelete(x uint32) (loaded, ok bool) {
 // Range does not necessarily correspond to any consistent snapshot of the set's
 // contents: no item will be visited more than once, but if the item
 // is stored or deleted concurrently, Range may reflect any mapping for that item.
+// use Snapshot for a consistent view.
 //
 // Range may be O(N) with the worst time complexity.
 // example set: {31,63,...,32*n-1}
 // this will case O(max),max give in init.
 func (s *Static) Range(f func(x uint32) bool) {
-	sLen := uint32(s.getLen())
-	for i := 0; i < int(sLen); i++ {
+	sLen := int(s.getLen())
+	for i := 0; i < sLen; i++ {
+		// jump to each set bit by trailing zeros count.
+		for item := s.load(i); item != 0; item &= item - 1 {
+			if !f(uint32(i<<5 + bits.TrailingZeros32(item))) {
+				return
+			}
+		}
+	}
+}
+
+// RangeWords calls f sequentially for each 64 items word of the set,
+// base is the first item of word, bit j of word is item base+j.
+// the empty words are skipped.
+// If f returns false, range stops the iteration.
+// time complexity: O(N/64)
+func (s *Static) RangeWords(f func(base uint32, word uint64) bool) {
+	walkWords(s, f)
+}
+
+// RangeFrom calls f sequentially for each item >= lo in the set.
+// If f returns false, range stops the iteration.
+func (s *Static) RangeFrom(lo uint32, f func(x uint32) bool) {
+	walkBetween(s, lo, s.getMax(), f)
+}
+
+// RangeBetween calls f sequentially for each item in [lo,hi] of the set.
+// it seek to the word hold lo, not scan from 0.
+// If f returns false, range stops the iteration.
+//
+// RangeBetween does not necessarily correspond to any consistent snapshot of the set's
+// contents, the same as Range.
+// time complexity: O((hi-lo)/32)
+func (s *Static) RangeBetween(lo, hi uint32, f func(x uint32) bool) {
+	walkBetween(s, lo, hi, f)
+}
+
+// RangeReverse calls f sequentially for each item in the set,
+// from the biggest to the smallest.
+// If f returns false, range stops the iteration.
+// time complexity: O(N/32)
+func (s *Static) RangeReverse(f func(x uint32) bool) {
+	walkReverse(s, 0, s.getMax(), f)
+}
+
+// RangeReverseBetween calls f sequentially for each item in [lo,hi] of the set,
+// from hi to lo.
+// If f returns false, range stops the iteration.
+// time complexity: O((hi-lo)/32)
+func (s *Static) RangeReverseBetween(lo, hi uint32, f func(x uint32) bool) {
+	walkReverse(s, lo, hi, f)
+}
+
+// Min return the smallest item in the set.
+// ok is false if the set is empty.
+// time complexity: O(N/32)
+func (s *Static) Min() (x uint32, ok bool) {
+	return nextBit(s, 0)
+}
+
+// Max return the biggest item in the set.
+// ok is false if the set is empty.
+// time complexity: O(N/32)
+func (s *Static) Max() (x uint32, ok bool) {
+	return prevBit(s, s.getMax())
+}
+
+// Next return the smallest item in the set at or after x.
+// ok is false if no such item.
+// time complexity: O(N/32)
+func (s *Static) Next(x uint32) (next uint32, ok bool) {
+	if x > s.getMax() {
+		return 0, false
+	}
+	return nextBit(s, x)
+}
+
+// Prev return the biggest item in the set at or before x.
+// ok is false if no such item.
+// time complexity: O(N/32)
+func (s *Static) Prev(x uint32) (prev uint32, ok bool) {
+	return prevBit(s, x)
+}
+
+// AddRange adds all items in [lo,hi] to the set.
+// return false if hi overflow bigger than max.
+// time complexity: O((hi-lo)/32)
+func (s *Static) AddRange(lo, hi uint32) bool {
+	return s.updateRange(lo, hi, opAdd)
+}
+
+// RemoveRange remove all items in [lo,hi] from the set.
+// return false if hi overflow bigger than max.
+// time complexity: O((hi-lo)/32)
+func (s *Static) RemoveRange(lo, hi uint32) bool {
+	return s.updateRange(lo, hi, opRemove)
+}
+
+// FlipRange toggle all items in [lo,hi],
+// item in set is removed, item not in set is added.
+// return false if hi overflow bigger than max.
+// time complexity: O((hi-lo)/32)
+func (s *Static) FlipRange(lo, hi uint32) bool {
+	return s.updateRange(lo, hi, opFlip)
+}
+
+// updateRange set each word in [lo,hi] to op(item,mask) with CAS.
+func (s *Static) updateRange(lo, hi uint32, op func(item, mask uint32) uint32) bool {
+	s.onceInit(initSize)
+	if hi > s.getMax() {
+		return false
+	}
+	s.updateWords(int(lo>>5), int(hi>>5), func(idx int, item uint32) uint32 {
+		return op(item, rangeMask(32, lo, hi, idx))
+	})
+	return true
+}
+
+// updateWords set each word idx in [from,to] to f(idx,item) with CAS,
+// the items bigger than max are dropped.
+func (s *Static) updateWords(from, to int, f func(idx int, item uint32) uint32) {
+	s.onceInit(initSize)
+	max := s.getMax()
+	if last := int(max >> 5); to > last {
+		to = last
+	}
+	if from > to || s.overflow(to) {
+		return
+	}
+	for i := from; i <= to; i++ {
+		mask := validMask(32, max, i)
+		s.modify(i, func(item uint32) uint32 { return f(i, item) & mask })
+	}
+}
+
+// modify set word i to f(word i) with CAS, keep count and rank index.
+// the shared page is not copied if the word not change.
+func (s *Static) modify(i int, f func(item uint32) uint32) {
+	for {
 		item := s.load(i)
-		if item == 0 {
-			continue
+		n := f(item)
+		if n == item {
+			return
 		}
-		for j := 0; j < 32; j++ {
-			if item == 0 {
-				break
-			}
-			if item&1 == 1 {
-				if !f(uint32(32*i + j)) {
-					return
-				}
-			}
-			item >>= 1
+		if s.cas(i, item, n) {
+			s.account(i, bits.OnesCount32(n)-bits.OnesCount32(item))
+			return
+		}
+	}
+}
+
+// Not return a new set of the items in [0,max] but not in s.
+// time complexity: O(max/32)
+func (s *Static) Not() *Static {
+	var p Static
+	p.OnceInit(int(s.getMax()))
+	notInto(&p, s, p.getMax())
+	return &p
+}
+
+// UnionWith set s to the union set of s and t in place.
+// each word of s is update with CAS.
+// time complexity: O(N/32)
+func (s *Static) UnionWith(t Set) { withOperation(s, t, opUnion) }
+
+// IntersectWith set s to the intersection set of s and t in place.
+// each word of s is update with CAS.
+// time complexity: O(N/32)
+func (s *Static) IntersectWith(t Set) { withOperation(s, t, opIntersect) }
+
+// DifferenceWith remove the items in t from s in place.
+// each word of s is update with CAS.
+// time complexity: O(N/32)
+func (s *Static) DifferenceWith(t Set) { withOperation(s, t, opDifference) }
+
+// SymmetricDifferenceWith set s to the items in s or in t but not both in place.
+// each word of s is update with CAS.
+// time complexity: O(N/32)
+func (s *Static) SymmetricDifferenceWith(t Set) { withOperation(s, t, opComplement) }
+
+// EnableRank build a rank index of blocks item count,
+// make Rank, Select and CountRange O(log N).
+// the index is kept by Store and Delete once enable.
+//
+// EnableRank scan the set without stop writers,
+// call it before the set is shared between goroutines.
+func (s *Static) EnableRank() {
+	s.onceInit(initSize)
+	if s.getRank() != nil {
+		return
+	}
+	atomic.CompareAndSwapPointer(&s.rank, nil, unsafe.Pointer(newRankIndex(s)))
+}
+
+// Rank return the number of items in the set which <= x.
+// time complexity: O(N/32), O(log N) with rank index.
+func (s *Static) Rank(x uint32) int {
+	return rankBit(s, s.getRank(), x)
+}
+
+// Select return the k-th smallest item in the set, k start from 0.
+// ok is false if k >= the number of items.
+// time complexity: O(N/32), O(log N) with rank index.
+func (s *Static) Select(k int) (x uint32, ok bool) {
+	return selectBit(s, s.getRank(), k)
+}
+
+// CountRange return the number of items in the set which in [lo,hi].
+// time complexity: O((hi-lo)/32), O(log N) with rank index.
+func (s *Static) CountRange(lo, hi uint32) int {
+	if lo > hi {
+		return 0
+	}
+	if r := s.getRank(); r != nil {
+		n := rankBit(s, r, hi)
+		if lo > 0 {
+			n -= rankBit(s, r, lo-1)
 		}
+		return n
 	}
+	return countBit(s, lo, hi)
 }
 
 // overflow update current len to idx+1 if idx>len
@@ -0,0 +1,359 @@
+package set
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math/bits"
+)
+
+// stream format of a set, chunk by chunk,
+// so that encode and decode never hold the whole encoded form:
+//
+//	header  magic [4]byte "sets", version byte, kind byte, max uvarint, crc [4]byte
+//	chunk   tag byte, chunk index uvarint, payload length uvarint, payload, crc [4]byte
+//	end     tagEnd, count uvarint, crc [4]byte
+//
+// each chunk hold streamChunkWords 64 items words, the empty chunks are skipped.
+// tagDense payload: little endian uint64 words of chunk, the empty tail is trimmed.
+// tagSparse payload: uvarint delta of each item to prev item, the first to chunk base.
+// crc is crc32 IEEE of the bytes of header or chunk before it, little endian.
+const (
+	streamMagic      = "sets"
+	streamVersion    = 1
+	streamChunkWords = 1 << 10
+)
+
+const (
+	tagDense byte = iota
+	tagSparse
+	tagEnd
+)
+
+// Encoder write sets to a stream, a chunk each time.
+type Encoder struct {
+	w     *bufio.Writer
+	buf   []byte
+	words [streamChunkWords]uint64
+}
+
+// NewEncoder return a new encoder that write to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode write set s to the stream, s must be Static or Dynamic.
+// each chunk is write as dense words or sparse delta varint,
+// whichever is smaller.
+//
+// Encode does not correspond to any consistent snapshot of s,
+// the same as Range.
+func (e *Encoder) Encode(s Set) error {
+	var kind byte
+	var max uint32
+	switch ss := s.(type) {
+	case *Static:
+		ss.onceInit(initSize)
+		kind, max = kindStatic, ss.getMax()
+	case *Dynamic:
+		kind, max = kindDynamic, ss.getMax()
+	default:
+		return ErrKind
+	}
+	b, _ := toBitSet(s)
+	e.buf = append(e.buf[:0], streamMagic...)
+	e.buf = append(e.buf, streamVersion, kind)
+	e.buf = binary.AppendUvarint(e.buf, uint64(max))
+	if err := e.flush(); err != nil {
+		return err
+	}
+	count := 0
+	n := wordCount(b, 64)
+	for c := 0; c*streamChunkWords < n; c++ {
+		base := c * streamChunkWords
+		last := -1
+		for j := range e.words {
+			i := 2 * (base + j)
+			e.words[j] = uint64(loadWord(b, i, 32)) | uint64(loadWord(b, i+1, 32))<<32
+			if e.words[j] != 0 {
+				last = j
+				count += bits.OnesCount64(e.words[j])
+			}
+		}
+		if last < 0 {
+			continue
+		}
+		if err := e.writeChunk(c, e.words[:last+1]); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf[:0], tagEnd)
+	e.buf = binary.AppendUvarint(e.buf, uint64(count))
+	if err := e.flush(); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// writeChunk write chunk c of words with the smaller payload.
+func (e *Encoder) writeChunk(c int, words []uint64) error {
+	dense := 8 * len(words)
+	e.buf = append(e.buf[:0], tagSparse)
+	e.buf = binary.AppendUvarint(e.buf, uint64(c))
+	head := len(e.buf)
+	// sparse payload after the max length of payload length.
+	e.buf = append(e.buf, make([]byte, binary.MaxVarintLen64)...)
+	start := len(e.buf)
+	prev := uint64(0)
+	for j, w := range words {
+		for ; w != 0; w &= w - 1 {
+			x := uint64(j)*64 + uint64(bits.TrailingZeros64(w))
+			e.buf = binary.AppendUvarint(e.buf, x-prev)
+			prev = x
+		}
+		if len(e.buf)-start >= dense {
+			break
+		}
+	}
+	if size := len(e.buf) - start; size < dense {
+		// move the payload to the end of payload length.
+		n := binary.PutUvarint(e.buf[head:], uint64(size))
+		copy(e.buf[head+n:], e.buf[start:])
+		e.buf = e.buf[:head+n+size]
+		return e.flush()
+	}
+	e.buf[0] = tagDense
+	e.buf = binary.AppendUvarint(e.buf[:head], uint64(dense))
+	for _, w := range words {
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, w)
+	}
+	return e.flush()
+}
+
+// flush write buf with crc to w.
+func (e *Encoder) flush() error {
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, crc32.ChecksumIEEE(e.buf))
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// Decoder read sets from a stream, a chunk each time.
+type Decoder struct {
+	r   byteReader
+	crc uint32
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// NewDecoder return a new decoder that read from r.
+// If r does not also implement io.ByteReader,
+// it will be wrapped in a bufio.Reader, which may read past the set.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br}
+}
+
+// Decode read the next set in the stream to s, s must be Static or Dynamic.
+// s is init with max of stream if not init yet, and cleared before load.
+// return ErrOverflow if some items bigger than max of s, the others still loaded.
+func (d *Decoder) Decode(s Set) error {
+	return d.decode(s, true)
+}
+
+// Merge read the next set in the stream and union into s chunk by chunk,
+// the items of s are kept, no temporary set is build.
+// s is init with max of stream if not init yet.
+// return ErrOverflow if some items bigger than max of s, the others still loaded.
+func (d *Decoder) Merge(s Set) error {
+	return d.decode(s, false)
+}
+
+func (d *Decoder) decode(s Set, clear bool) error {
+	var p wordSet
+	switch ss := s.(type) {
+	case *Static:
+		p = ss
+	case *Dynamic:
+		p = ss
+	default:
+		return ErrKind
+	}
+	d.crc = 0
+	var magic [len(streamMagic) + 2]byte
+	if n, err := d.read(magic[:]); err != nil {
+		if n > 0 {
+			return unexpectedEOF(err)
+		}
+		return err
+	}
+	if string(magic[:len(streamMagic)]) != streamMagic {
+		return ErrFormat
+	}
+	if magic[len(streamMagic)] != streamVersion {
+		return ErrVersion
+	}
+	if k := magic[len(streamMagic)+1]; k != kindStatic && k != kindDynamic {
+		return ErrKind
+	}
+	max, err := d.readUvarint()
+	if err != nil || max > 1<<32-1 {
+		return errFormat(err)
+	}
+	if err := d.checkCRC(); err != nil {
+		return err
+	}
+	if max > uint64(maximum) {
+		max = uint64(maximum)
+	}
+	switch ss := s.(type) {
+	case *Static:
+		ss.OnceInit(int(max))
+	case *Dynamic:
+		if max >= uint64(maximum) {
+			// grow as need, not preallocate the whole range.
+			ss.OnceInit(0)
+		} else {
+			ss.OnceInit(int(max))
+		}
+	}
+	if clear {
+		Clear(s)
+	}
+	overflow, count := false, 0
+	words := make([]uint64, streamChunkWords)
+	for {
+		tag, err := d.readByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if tag == tagEnd {
+			n, err := d.readUvarint()
+			if err != nil {
+				return errFormat(err)
+			}
+			if err := d.checkCRC(); err != nil {
+				return err
+			}
+			if n != uint64(count) {
+				return ErrFormat
+			}
+			break
+		}
+		c, err1 := d.readUvarint()
+		size, err2 := d.readUvarint()
+		if err1 != nil || err2 != nil || tag > tagSparse || c >= 1<<32/64/streamChunkWords || size > 8*streamChunkWords {
+			return ErrFormat
+		}
+		payload := make([]byte, size)
+		if _, err := d.read(payload); err != nil {
+			return unexpectedEOF(err)
+		}
+		if err := d.checkCRC(); err != nil {
+			return err
+		}
+		n, err := decodeChunk(tag, payload, words)
+		if err != nil {
+			return err
+		}
+		count += n
+		base := int(c) * streamChunkWords
+		for j, w := range words {
+			if !orWord64(p, base+j, w) {
+				overflow = true
+			}
+		}
+	}
+	if overflow {
+		return ErrOverflow
+	}
+	return nil
+}
+
+// decodeChunk decode the payload to words, return the number of items.
+func decodeChunk(tag byte, payload []byte, words []uint64) (int, error) {
+	for j := range words {
+		words[j] = 0
+	}
+	count := 0
+	if tag == tagDense {
+		if len(payload)%8 != 0 {
+			return 0, ErrFormat
+		}
+		for j := 0; j < len(payload)/8; j++ {
+			words[j] = binary.LittleEndian.Uint64(payload[8*j:])
+			count += bits.OnesCount64(words[j])
+		}
+		return count, nil
+	}
+	x := uint64(0)
+	for len(payload) > 0 {
+		delta, n := binary.Uvarint(payload)
+		if n <= 0 || (count > 0 && delta == 0) {
+			return 0, ErrFormat
+		}
+		payload = payload[n:]
+		if x += delta; x >= 64*uint64(len(words)) {
+			return 0, ErrFormat
+		}
+		words[x/64] |= 1 << (x % 64)
+		count += 1
+	}
+	return count, nil
+}
+
+// read full p and update crc.
+func (d *Decoder) read(p []byte) (int, error) {
+	n, err := io.ReadFull(d.r, p)
+	d.crc = crc32.Update(d.crc, crc32.IEEETable, p[:n])
+	return n, err
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == nil {
+		d.crc = crc32.Update(d.crc, crc32.IEEETable, []byte{b})
+	}
+	return b, err
+}
+
+func (d *Decoder) readUvarint() (uint64, error) {
+	return binary.ReadUvarint(byteFunc(d.readByte))
+}
+
+// checkCRC read the crc and compare with crc of bytes read,
+// then start a new crc.
+func (d *Decoder) checkCRC() error {
+	var b [4]byte
+	if _, err := io.ReadFull(d.r, b[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	sum := d.crc
+	d.crc = 0
+	if binary.LittleEndian.Uint32(b[:]) != sum {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// byteFunc is a io.ByteReader of function.
+type byteFunc func() (byte, error)
+
+func (f byteFunc) ReadByte() (byte, error) { return f() }
+
+// errFormat return ErrFormat for the bad varint, or io.ErrUnexpectedEOF.
+func errFormat(err error) error {
+	if err == nil {
+		return ErrFormat
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return io.ErrUnexpectedEOF
+	}
+	return ErrFormat
+}
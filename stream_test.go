@@ -0,0 +1,175 @@
+package set_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+func TestEncoder(t *testing.T) {
+	sparse := []uint32{0, 7, 300, 9999, 70000, 199999}
+	var dense []uint32
+	for i := uint32(100); i < 150000; i++ {
+		if i%3 != 0 {
+			dense = append(dense, i)
+		}
+	}
+	for _, items := range [][]uint32{nil, sparse, dense} {
+		for _, src := range []func() Interface{
+			func() Interface { return &set.Static{} },
+			func() Interface { return &set.Dynamic{} },
+		} {
+			for _, dst := range []func() Interface{
+				func() Interface { return &set.Static{} },
+				func() Interface { return &set.Dynamic{} },
+			} {
+				s, d := src(), dst()
+				t.Run(fmt.Sprintf("%T-%T-%d", s, d, len(items)), func(t *testing.T) {
+					s.OnceInit(200000)
+					set.Adds(s, items...)
+					var buf bytes.Buffer
+					if err := set.NewEncoder(&buf).Encode(s); err != nil {
+						t.Fatal(err)
+					}
+					// not init yet, use max of stream.
+					if err := set.NewDecoder(&buf).Decode(d); err != nil {
+						t.Fatal(err)
+					}
+					if !set.Equal(s, d) || set.Size(d) != len(items) {
+						t.Fatalf("Decode() = %v want %v", set.Size(d), set.Size(s))
+					}
+					if !d.Store(200000) || d.Store(200001) {
+						t.Fatalf("Decode() max err")
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestEncoderMany(t *testing.T) {
+	sets := []set.Set{
+		set.NewStatic(100, 1, 2, 3),
+		set.NewStatic(100),
+		set.NewStatic(1<<20, 5, 1<<19, 1<<20),
+	}
+	var buf bytes.Buffer
+	enc := set.NewEncoder(&buf)
+	for _, s := range sets {
+		if err := enc.Encode(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dec := set.NewDecoder(&buf)
+	for _, s := range sets {
+		var d set.Dynamic
+		if err := dec.Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+		if !set.Equal(s, &d) {
+			t.Fatalf("Decode() = %v want %v", &d, s)
+		}
+	}
+	if err := dec.Decode(&set.Static{}); err != io.EOF {
+		t.Fatalf("Decode() end = %v", err)
+	}
+}
+
+func TestDecoderMerge(t *testing.T) {
+	var buf bytes.Buffer
+	enc := set.NewEncoder(&buf)
+	enc.Encode(set.NewStatic(1000, 1, 2, 500))
+	enc.Encode(set.NewDynamic(1000, 2, 3, 999))
+	d := set.NewStatic(1000, 0, 1)
+	dec := set.NewDecoder(&buf)
+	for i := 0; i < 2; i++ {
+		if err := dec.Merge(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := set.NewStatic(1000, 0, 1, 2, 3, 500, 999)
+	if !set.Equal(d, want) {
+		t.Fatalf("Merge() = %v want %v", d, want)
+	}
+
+	// items bigger than max of d are dropped.
+	buf.Reset()
+	enc.Encode(set.NewStatic(1000, 7, 999))
+	small := set.NewStatic(100, 1)
+	if err := set.NewDecoder(&buf).Merge(small); !errors.Is(err, set.ErrOverflow) {
+		t.Fatalf("Merge() overflow = %v", err)
+	}
+	if !set.Equal(small, set.NewStatic(100, 1, 7)) {
+		t.Fatalf("Merge() overflow = %v", small)
+	}
+}
+
+func TestDecoderError(t *testing.T) {
+	var s set.Static
+	s.OnceInit(100000)
+	for i := uint32(0); i < 100000; i += 7 {
+		s.Store(i)
+	}
+	var buf bytes.Buffer
+	if err := set.NewEncoder(&buf).Encode(&s); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for i := 0; i < len(data); i += 97 {
+		bad := append([]byte(nil), data...)
+		bad[i] ^= 0x10
+		if err := set.NewDecoder(bytes.NewReader(bad)).Decode(&set.Dynamic{}); err == nil {
+			t.Fatalf("Decode() corrupt byte %d no error", i)
+		}
+	}
+	// corrupt payload of first chunk.
+	bad := append([]byte(nil), data...)
+	bad[len(data)/2] ^= 1
+	if err := set.NewDecoder(bytes.NewReader(bad)).Decode(&set.Dynamic{}); !errors.Is(err, set.ErrChecksum) {
+		t.Fatalf("Decode() corrupt = %v", err)
+	}
+	for _, n := range []int{1, 10, len(data) / 2, len(data) - 1} {
+		if err := set.NewDecoder(bytes.NewReader(data[:n])).Decode(&set.Dynamic{}); err != io.ErrUnexpectedEOF {
+			t.Fatalf("Decode() truncate %d = %v", n, err)
+		}
+	}
+	if err := set.NewEncoder(&buf).Encode(&set.Roaring{}); !errors.Is(err, set.ErrKind) {
+		t.Fatalf("Encode() roaring = %v", err)
+	}
+	if err := set.NewDecoder(bytes.NewReader(data)).Decode(&set.Roaring{}); !errors.Is(err, set.ErrKind) {
+		t.Fatalf("Decode() roaring = %v", err)
+	}
+}
+
+// TestDecoderMax the set is init with the max of stream,
+// decoded straight into the set.
+func TestDecoderMax(t *testing.T) {
+	var st set.Static
+	st.OnceInit(2000000)
+	st.Store(5)
+	var buf bytes.Buffer
+	if err := set.NewEncoder(&buf).Encode(&st); err != nil {
+		t.Fatal(err)
+	}
+	var s set.Static
+	if err := set.NewDecoder(&buf).Decode(&s); err != nil || s.String() != "{5}" {
+		t.Fatalf("Decode() = %v %v", &s, err)
+	}
+	if !s.Store(2000000) || s.Store(2000001) {
+		t.Fatalf("Decode() max not kept")
+	}
+	// a Dynamic with max in stream.
+	big := set.NewDynamic(1<<25, 1, 1<<25).(*set.Dynamic)
+	buf.Reset()
+	if err := set.NewEncoder(&buf).Encode(big); err != nil {
+		t.Fatal(err)
+	}
+	var c set.Dynamic
+	if err := set.NewDecoder(&buf).Merge(&c); err != nil || !set.Equal(&c, big) || c.Store(1<<25+1) {
+		t.Fatalf("Merge() big = %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package set
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// MarshalText implements the encoding.TextMarshaler interface.
+// the set is encode in run notation, like {1-5 9 12-20}.
+func (s *Static) MarshalText() ([]byte, error) { return marshalText(s) }
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// text is in run notation, like {1-5 9 12-20} or 1-5,9,12-20.
+// the set is init with the biggest item as max if not init yet,
+// and cleared before load.
+func (s *Static) UnmarshalText(text []byte) error { return unmarshalText(s, string(text), true) }
+
+// Set implements the flag.Value interface,
+// add the items of value in run notation, like 0-15,32.
+// the flag can be given many times, the items are added to the set.
+// the set is init with the biggest item of the first value if not init yet,
+// init it before to hold a bigger item later,
+// return ErrOverflow if some items out of range, the others still added.
+func (s *Static) Set(value string) error { return unmarshalText(s, value, false) }
+
+// MarshalText implements the encoding.TextMarshaler interface.
+// the set is encode in run notation, like {1-5 9 12-20}.
+func (s *Dynamic) MarshalText() ([]byte, error) { return marshalText(s) }
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// text is in run notation, like {1-5 9 12-20} or 1-5,9,12-20.
+// the set is init with the biggest item as max if not init yet,
+// and cleared before load.
+func (s *Dynamic) UnmarshalText(text []byte) error { return unmarshalText(s, string(text), true) }
+
+// Set implements the flag.Value interface,
+// add the items of value in run notation, like 0-15,32.
+// the flag can be given many times, the items are added to the set.
+// the set is init without max if not init yet, and grow as need.
+func (s *Dynamic) Set(value string) error { return unmarshalText(s, value, false) }
+
+// MarshalText implements the encoding.TextMarshaler interface.
+// the set is encode in run notation, like {-5--3 9 12-20}.
+func (s *Base) MarshalText() ([]byte, error) { return marshalText(s) }
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// text is in run notation, like {-5--3 9 12-20} or -5--3,9,12-20.
+// the set is init with the smallest and biggest item as min and max
+// if not init yet, and cleared before load.
+func (s *Base) UnmarshalText(text []byte) error { return unmarshalText(s, string(text), true) }
+
+// Set implements the flag.Value interface,
+// add the items of value in run notation, like 0-15,32.
+// the flag can be given many times, the items are added to the set.
+// the set is init with the range of the first value if not init yet,
+// init it before to hold the items out of range later,
+// return ErrOverflow if some items out of range, the others still added.
+func (s *Base) Set(value string) error { return unmarshalText(s, value, false) }
+
+// Parse return a Static set of the items in run notation,
+// like {1-5 9 12-20} or 1-5,9,12-20,
+// the max of set is the biggest item.
+func Parse(text string) (Set, error) {
+	var s Static
+	if err := unmarshalText(&s, text, true); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// marshalText encode the items of s in run notation.
+func marshalText(s interface{}) ([]byte, error) {
+	buf := []byte{'{'}
+	err := walkRuns(s, func(lo, hi int64) {
+		if len(buf) > len("{") {
+			buf = append(buf, ' ')
+		}
+		buf = strconv.AppendInt(buf, lo, 10)
+		if hi > lo {
+			buf = append(buf, '-')
+			buf = strconv.AppendInt(buf, hi, 10)
+		}
+	})
+	return append(buf, '}'), err
+}
+
+// unmarshalText add the items of text in run notation to s.
+func unmarshalText(s interface{}, text string, clear bool) error {
+	runs, err := parseRuns(text)
+	if err != nil {
+		return err
+	}
+	return loadRuns(s, runs, clear)
+}
+
+// parseRuns parse text in run notation, the braces are optional,
+// runs are separated by space or comma, a run is "x" or "lo-hi".
+func parseRuns(text string) (jsonRuns, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") || strings.HasSuffix(text, "}") {
+		if len(text) < 2 || text[0] != '{' || text[len(text)-1] != '}' {
+			return nil, fmt.Errorf("set: unbalanced braces in %q", text)
+		}
+		text = text[1 : len(text)-1]
+	}
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	runs := make(jsonRuns, len(fields))
+	for i, f := range fields {
+		// skip the sign of lo.
+		j := strings.IndexByte(f[1:], '-') + 1
+		if j == 0 {
+			x, err := strconv.ParseInt(f, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("set: invalid item %q", f)
+			}
+			runs[i] = [2]int64{x, x}
+			continue
+		}
+		lo, err1 := strconv.ParseInt(f[:j], 10, 64)
+		hi, err2 := strconv.ParseInt(f[j+1:], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("set: invalid range %q", f)
+		}
+		if lo > hi {
+			return nil, fmt.Errorf("set: invalid range %q", f)
+		}
+		runs[i] = [2]int64{lo, hi}
+	}
+	for _, v := range runs {
+		if v[0] < math.MinInt32 || v[1] > math.MaxUint32 {
+			return nil, fmt.Errorf("set: range %d-%d overflow", v[0], v[1])
+		}
+	}
+	return runs, nil
+}
+
+// loadRuns add the runs to s, s is Static, Dynamic or Base.
+// s is init with the range of runs if not init yet,
+// a Dynamic without max if not clear, and cleared first if clear.
+// s is never re-init, the runs out of range return ErrOverflow.
+func loadRuns(s interface{}, runs jsonRuns, clear bool) error {
+	var lo, hi int64
+	switch ss := s.(type) {
+	case *Static:
+		if len(runs) > 0 {
+			ss.OnceInit(int(runs.max()))
+		}
+		if clear {
+			Clear(ss)
+		}
+		hi = int64(ss.getMax())
+	case *Dynamic:
+		if !clear {
+			// a flag given many times, grow as need.
+			ss.OnceInit(0)
+		} else if len(runs) > 0 {
+			ss.OnceInit(int(runs.max()))
+		}
+		if clear {
+			Clear(ss)
+		}
+		hi = int64(ss.getMax())
+	case *Base:
+		if len(runs) > 0 {
+			ss.Init(int(min64(runs.max(), math.MaxInt32)), int(runs.min()))
+		}
+		if clear {
+			Clear(&ss.Static)
+		}
+		lo, hi = int64(atomic.LoadInt32(&ss.min)), int64(atomic.LoadInt32(&ss.max))
+	default:
+		return fmt.Errorf("set: can't load runs to %T", s)
+	}
+	return runs.addTo(s, lo, hi)
+}
+
+// walkRuns calls f for each run [lo,hi] of the items of s in ascending order.
+func walkRuns(s interface{}, f func(lo, hi int64)) error {
+	walk, err := jsonWalk(s)
+	if err != nil {
+		return err
+	}
+	var lo, hi int64
+	empty := true
+	walk(func(x int64) bool {
+		switch {
+		case empty:
+			lo, hi, empty = x, x, false
+		case x == hi+1:
+			hi = x
+		default:
+			f(lo, hi)
+			lo, hi = x, x
+		}
+		return true
+	})
+	if !empty {
+		f(lo, hi)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package set_test
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/min1324/set"
+)
+
+type textSet interface {
+	Interface
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+	flag.Value
+}
+
+func TestText(t *testing.T) {
+	for _, c := range []struct {
+		s, d textSet
+	}{
+		{&set.Static{}, &set.Static{}},
+		{&set.Dynamic{}, &set.Dynamic{}},
+		{&set.Dynamic{}, &set.Static{}},
+	} {
+		t.Run(fmt.Sprintf("%T-%T", c.s, c.d), func(t *testing.T) {
+			c.s.OnceInit(1 << 20)
+			for i := uint32(0); i <= 1000000; i++ {
+				c.s.Store(i)
+			}
+			c.s.Delete(500)
+			text, err := c.s.MarshalText()
+			if err != nil || string(text) != "{0-499 501-1000000}" {
+				t.Fatalf("MarshalText() = %s %v", text, err)
+			}
+			if err := c.d.UnmarshalText(text); err != nil {
+				t.Fatal(err)
+			}
+			if !set.Equal(c.s, c.d) || c.d.Store(1000001) {
+				t.Fatalf("UnmarshalText() not equal")
+			}
+			if err := c.d.UnmarshalText([]byte("{3 1-2 7}")); err != nil || c.d.String() != "{1 2 3 7}" {
+				t.Fatalf("UnmarshalText() = %v %v", c.d, err)
+			}
+			for _, bad := range []string{"{1", "1}", "a", "1-", "-1", "5-3", "1--2", "4294967296"} {
+				if err := c.d.UnmarshalText([]byte(bad)); err == nil {
+					t.Fatalf("UnmarshalText(%q) no err", bad)
+				}
+			}
+		})
+	}
+}
+
+func TestTextBase(t *testing.T) {
+	b := set.NewBase(100, -100)
+	b.AddRange(-5, -3)
+	b.Add(9)
+	text, _ := b.MarshalText()
+	if string(text) != "{-5--3 9}" {
+		t.Fatalf("MarshalText() = %s", text)
+	}
+	var c set.Base
+	if err := c.UnmarshalText(text); err != nil || c.String() != b.String() {
+		t.Fatalf("UnmarshalText() = %v %v", &c, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := set.Parse(" {1-5 9 12-14} ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.(fmt.Stringer).String(); got != "{1 2 3 4 5 9 12 13 14}" {
+		t.Fatalf("Parse() = %s", got)
+	}
+	if s.Store(15) {
+		t.Fatalf("Parse() max err")
+	}
+	if s, err := set.Parse("{}"); err != nil || !set.Null(s) {
+		t.Fatalf("Parse({}) = %v %v", s, err)
+	}
+	if _, err := set.Parse("{1 x}"); err == nil {
+		t.Fatalf("Parse({1 x}) no err")
+	}
+}
+
+func TestFlag(t *testing.T) {
+	var shards set.Dynamic
+	shards.OnceInit(0)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&shards, "shards", "shard ids")
+	if err := fs.Parse([]string{"--shards=0-15,32", "--shards", "40 41"}); err != nil {
+		t.Fatal(err)
+	}
+	if set.Size(&shards) != 19 || !shards.Load(15) || !shards.Load(32) || !shards.Load(41) || shards.Load(16) {
+		t.Fatalf("flag = %v", &shards)
+	}
+	if err := fs.Parse([]string{"--shards=1-x"}); err == nil {
+		t.Fatalf("flag 1-x no err")
+	}
+}
+
+// TestFlagRepeat the later flag out of range of the first one,
+// a Dynamic grow to hold all items, a Static or Base init before hold them,
+// or report ErrOverflow.
+func TestFlagRepeat(t *testing.T) {
+	var s, z set.Static
+	var d set.Dynamic
+	s.OnceInit(1000)
+	b := set.NewBase(100, -10)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&s, "static", "static ids")
+	fs.Var(&d, "dynamic", "dynamic ids")
+	fs.Var(b, "base", "base ids")
+	args := []string{
+		"--static=0-15", "--static=32", "--static=1000",
+		"--dynamic=0-15", "--dynamic=32", "--dynamic=100000",
+		"--base=0-15", "--base=32", "--base=-5",
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := s.MarshalText()
+	dt, _ := d.MarshalText()
+	bt, _ := b.MarshalText()
+	if string(st) != "{0-15 32 1000}" || string(dt) != "{0-15 32 100000}" || string(bt) != "{-5 0-15 32}" {
+		t.Fatalf("flag = %s %s %s", st, dt, bt)
+	}
+	if err := z.Set("0-15"); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Set("32"); !errors.Is(err, set.ErrOverflow) {
+		t.Fatalf("flag out of range = %v", err)
+	}
+	if zt, _ := z.MarshalText(); string(zt) != "{0-15}" {
+		t.Fatalf("flag out of range = %s", zt)
+	}
+}
+
+func TestMapString(t *testing.T) {
+	var m set.Map
+	m.Add("a b")
+	if got := m.String(); got != `{"a b"}` {
+		t.Fatalf("String() = %s", got)
+	}
+	m.Remove("a b")
+	m.Add(1.5)
+	if got := m.String(); got != "{1.5}" {
+		t.Fatalf("String() = %s", got)
+	}
+}
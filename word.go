@@ -0,0 +1,384 @@
+package set
+
+import "math/bits"
+
+// bitSet is a opSet that know the number of item in each word.
+// Static hold 32 item in each word,
+// dynEntry hold 16 item in each word, the height bits is freezeBit.
+type bitSet interface {
+	opSet
+
+	// wordBits return the number of item in each word.
+	wordBits() uint32
+}
+
+// nextBit return the smallest item in s which >= x.
+// skip the empty words, and use trailing zeros count in a word.
+//
+// time complexity: O(N/32)
+func nextBit(s bitSet, x uint32) (uint32, bool) {
+	w := s.wordBits()
+	slen := int(s.getLen())
+	idx, mod := int(x/w), x%w
+	if idx >= slen {
+		return 0, false
+	}
+	// clear the item < x in word idx
+	item := (s.load(idx) >> mod) << mod
+	for item == 0 {
+		idx += 1
+		if idx >= slen {
+			return 0, false
+		}
+		item = s.load(idx)
+	}
+	return uint32(idx)*w + uint32(bits.TrailingZeros32(item)), true
+}
+
+// prevBit return the biggest item in s which <= x.
+// skip the empty words, and use leading zeros count in a word.
+//
+// time complexity: O(N/32)
+func prevBit(s bitSet, x uint32) (uint32, bool) {
+	w := s.wordBits()
+	slen := int(s.getLen())
+	if slen == 0 {
+		return 0, false
+	}
+	idx, mod := int(x/w), x%w
+	var item uint32
+	if idx >= slen {
+		idx = slen - 1
+		item = s.load(idx)
+	} else {
+		// clear the item > x in word idx
+		item = s.load(idx) & (2<<mod - 1)
+	}
+	for item == 0 {
+		idx -= 1
+		if idx < 0 {
+			return 0, false
+		}
+		item = s.load(idx)
+	}
+	return uint32(idx)*w + uint32(31-bits.LeadingZeros32(item)), true
+}
+
+// walkWords calls f sequentially for each none empty 64 items word of s.
+// If f returns false, walkWords stops the iteration.
+func walkWords(s bitSet, f func(base uint32, word uint64) bool) {
+	w := s.wordBits()
+	n := int(64 / w)
+	slen := int(s.getLen())
+	for i := 0; i < slen; i += n {
+		var word uint64
+		for j := 0; j < n && i+j < slen; j++ {
+			word |= uint64(s.load(i+j)) << (uint32(j) * w)
+		}
+		if word != 0 && !f(uint32(i)*w, word) {
+			return
+		}
+	}
+}
+
+// popCount return the number of items in s.
+// time complexity: O(N/32)
+func popCount(s bitSet) int {
+	n := 0
+	slen := int(s.getLen())
+	for i := 0; i < slen; i++ {
+		n += bits.OnesCount32(s.load(i))
+	}
+	return n
+}
+
+// walkBetween calls f sequentially for each item in [lo,hi] of s,
+// start from the word hold lo.
+// If f returns false, walkBetween stops the iteration.
+func walkBetween(s bitSet, lo, hi uint32, f func(x uint32) bool) {
+	if lo > hi {
+		return
+	}
+	w := s.wordBits()
+	end := min(int(hi/w), int(s.getLen())-1)
+	for idx := int(lo / w); idx <= end; idx++ {
+		item := s.load(idx) & rangeMask(w, lo, hi, idx)
+		for item != 0 {
+			if !f(uint32(idx)*w + uint32(bits.TrailingZeros32(item))) {
+				return
+			}
+			// clear the lowest item
+			item &= item - 1
+		}
+	}
+}
+
+// walkReverse calls f sequentially for each item in [lo,hi] of s,
+// from high to low, start from the word hold hi.
+// If f returns false, walkReverse stops the iteration.
+func walkReverse(s bitSet, lo, hi uint32, f func(x uint32) bool) {
+	if lo > hi {
+		return
+	}
+	w := s.wordBits()
+	start := min(int(hi/w), int(s.getLen())-1)
+	for idx := start; idx >= int(lo/w); idx-- {
+		item := s.load(idx) & rangeMask(w, lo, hi, idx)
+		for item != 0 {
+			j := uint32(31 - bits.LeadingZeros32(item))
+			if !f(uint32(idx)*w + j) {
+				return
+			}
+			// clear the highest item
+			item &^= 1 << j
+		}
+	}
+}
+
+// rangeMask return the mask of items in [lo,hi] of word idx,
+// w is wordBits.
+func rangeMask(w, lo, hi uint32, idx int) uint32 {
+	if lo > hi {
+		return 0
+	}
+	loIdx, hiIdx := int(lo/w), int(hi/w)
+	if idx < loIdx || idx > hiIdx {
+		return 0
+	}
+	var a, b uint32 = 0, w - 1
+	if idx == loIdx {
+		a = lo % w
+	}
+	if idx == hiIdx {
+		b = hi % w
+	}
+	return (2<<b - 1) &^ (1<<a - 1)
+}
+
+// validMask return the mask of items <= max in word idx,
+// w is wordBits.
+func validMask(w, max uint32, idx int) uint32 {
+	return rangeMask(w, 0, max, idx)
+}
+
+// opAdd set the mask bits of item.
+func opAdd(item, mask uint32) uint32 { return item | mask }
+
+// opRemove clear the mask bits of item.
+func opRemove(item, mask uint32) uint32 { return item &^ mask }
+
+// opFlip toggle the mask bits of item.
+func opFlip(item, mask uint32) uint32 { return item ^ mask }
+
+// wordSet is a Set can update it's words in place with CAS.
+type wordSet interface {
+	Set
+	bitSet
+
+	// updateWords set each word idx in [from,to] to f(idx,item) with CAS.
+	updateWords(from, to int, f func(idx int, item uint32) uint32)
+}
+
+// toBitSet return the words view of s,
+// ok is false if s is not Static, Dynamic or Snapshot.
+func toBitSet(s Set) (b bitSet, ok bool) {
+	switch ss := s.(type) {
+	case *Static:
+		return ss, true
+	case *Dynamic:
+		return ss.getEntry(), true
+	case *Snapshot:
+		return ss.bits, true
+	}
+	return nil, false
+}
+
+// loadWord return word i of s as w bits word,
+// w and s.wordBits() must be 16 or 32.
+func loadWord(s bitSet, i int, w uint32) uint32 {
+	sw := s.wordBits()
+	slen := int(s.getLen())
+	switch {
+	case sw == w:
+		if i >= slen {
+			return 0
+		}
+		return s.load(i)
+	case sw > w:
+		n := int(sw / w)
+		j := i / n
+		if j >= slen {
+			return 0
+		}
+		return s.load(j) >> (uint32(i%n) * w) & (1<<w - 1)
+	default:
+		n := int(w / sw)
+		var item uint32
+		for k := 0; k < n; k++ {
+			j := i*n + k
+			if j >= slen {
+				break
+			}
+			item |= s.load(j) << (uint32(k) * sw)
+		}
+		return item
+	}
+}
+
+// orWord64 set the bits of the 64 items word i in s,
+// the word is split to the word of s, and publish with one atomic op each.
+// return false if some items bigger than max are dropped.
+func orWord64(s wordSet, i int, word uint64) bool {
+	if word == 0 {
+		return true
+	}
+	w := s.wordBits()
+	n := int(64 / w)
+	max := s.getMax()
+	ok := true
+	for k := 0; k < n && word != 0; k++ {
+		idx := i*n + k
+		item := uint32(word) & (1<<w - 1)
+		mask := validMask(w, max, idx)
+		if item&^mask != 0 {
+			ok = false
+		}
+		if item &= mask; item != 0 {
+			switch ss := s.(type) {
+			case *Static:
+				ss.orWord(idx, item)
+			case *Dynamic:
+				ss.orWord(idx, item)
+			}
+		}
+		word >>= w
+	}
+	return ok
+}
+
+// notInto set each word of p to the complement of s's word,
+// the items bigger than max are dropped.
+func notInto(p wordSet, s bitSet, max uint32) {
+	w := p.wordBits()
+	p.updateWords(0, int(max/w), func(idx int, item uint32) uint32 {
+		return ^loadWord(s, idx, w) & validMask(w, max, idx)
+	})
+}
+
+// opWidth return the word bits to compare s and t.
+func opWidth(s, t bitSet) uint32 {
+	if s.wordBits() > t.wordBits() {
+		return s.wordBits()
+	}
+	return t.wordBits()
+}
+
+// wordCount return the number of w bits word hold the items of s.
+func wordCount(s bitSet, w uint32) int {
+	n := uint64(s.getLen()) * uint64(s.wordBits())
+	return int((n + uint64(w) - 1) / uint64(w))
+}
+
+// opWord return the flag operation of word x and y.
+func opWord(flag opFlag, x, y uint32) uint32 {
+	switch flag {
+	case opUnion:
+		return x | y
+	case opIntersect:
+		return x & y
+	case opDifference:
+		return x &^ y
+	case opComplement:
+		return x ^ y
+	}
+	return x
+}
+
+// withOperation set s to the flag operation of s and t in place.
+// each word of s is update with CAS.
+func withOperation(s wordSet, t Set, flag opFlag) {
+	tt, ok := toBitSet(t)
+	if !ok {
+		generalWith(s, t, flag)
+		return
+	}
+	w := s.wordBits()
+	slen, tlen := int(s.getLen()), wordCount(tt, w)
+	to := tlen
+	switch flag {
+	case opIntersect:
+		to = slen
+	case opDifference:
+		to = min(slen, tlen)
+	}
+	s.updateWords(0, to-1, func(idx int, item uint32) uint32 {
+		return opWord(flag, item, loadWord(tt, idx, w))
+	})
+}
+
+// generalWith set s to the flag operation of s and t,
+// item by item.
+// time complexity: O(N)
+func generalWith(s, t Set, flag opFlag) {
+	switch flag {
+	case opUnion:
+		t.Range(func(x uint32) bool {
+			s.Store(x)
+			return true
+		})
+	case opIntersect:
+		s.Range(func(x uint32) bool {
+			if !t.Load(x) {
+				s.Delete(x)
+			}
+			return true
+		})
+	case opDifference:
+		t.Range(func(x uint32) bool {
+			s.Delete(x)
+			return true
+		})
+	case opComplement:
+		t.Range(func(x uint32) bool {
+			if loaded, _ := s.LoadAndDelete(x); !loaded {
+				s.Store(x)
+			}
+			return true
+		})
+	}
+}
+
+// operationInto set dst to the flag operation of s and t.
+// if dst,s and t are Static or Dynamic, dst is update word by word,
+// or dst is cleared and store the items of the result.
+func operationInto(dst, s, t Set, flag opFlag) Set {
+	d, dok := dst.(wordSet)
+	if dok && Set(d) == s {
+		withOperation(d, t, flag)
+		return dst
+	}
+	ss, sok := toBitSet(s)
+	tt, tok := toBitSet(t)
+	if !dok || !sok || !tok {
+		p := operate(s, t, flag)
+		Clear(dst)
+		generalCopy(p, dst)
+		return dst
+	}
+	w := d.wordBits()
+	slen, tlen := wordCount(ss, w), wordCount(tt, w)
+	var need int
+	switch flag {
+	case opUnion, opComplement:
+		need = max(slen, tlen)
+	case opIntersect:
+		need = min(slen, tlen)
+	case opDifference:
+		need = slen
+	}
+	to := max(int(d.getLen()), need)
+	d.updateWords(0, to-1, func(idx int, item uint32) uint32 {
+		return opWord(flag, loadWord(ss, idx, w), loadWord(tt, idx, w))
+	})
+	return dst
+}